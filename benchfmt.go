@@ -0,0 +1,161 @@
+package hrtime
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BenchWriter writes results in the Go benchmark text format,
+// which can be consumed by benchstat and other tools:
+//
+//     goos: linux
+//     cpu: Intel(R) Xeon(R) Processor
+//     BenchmarkSleep 4096 1.1e+06 ns/op 1.05e+06 ns/p50 1.2e+06 ns/p90 1.3e+06 ns/p99
+//
+// Configuration lines are written only when their value changes.
+type BenchWriter struct {
+	// Percentiles are the percentiles written for each benchmark.
+	Percentiles []float64
+
+	w      io.Writer
+	config map[string]string
+}
+
+// NewBenchWriter creates a new writer that writes p50, p90 and p99 for each benchmark.
+func NewBenchWriter(w io.Writer) *BenchWriter {
+	return &BenchWriter{
+		Percentiles: []float64{0.5, 0.9, 0.99},
+		w:           w,
+		config:      map[string]string{},
+	}
+}
+
+// Config writes a "key: value" configuration line, when the value has changed.
+//
+// Key must not contain spaces or upper-case letters
+// and value must not contain line breaks.
+func (writer *BenchWriter) Config(key, value string) error {
+	if key == "" || strings.ContainsAny(key, " \t\r\n:") || strings.ToLower(key) != key {
+		return fmt.Errorf("invalid config key %q", key)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("invalid config value %q", value)
+	}
+	if previous, ok := writer.config[key]; ok && previous == value {
+		return nil
+	}
+	writer.config[key] = value
+	_, err := fmt.Fprintf(writer.w, "%s: %s\n", key, value)
+	return err
+}
+
+// Environment writes the environment as configuration lines,
+// e.g. hrtime.Environment().Map().
+func (writer *BenchWriter) Environment(env map[string]string) error {
+	for _, key := range environmentOrder(env) {
+		if err := writer.Config(key, env[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteHistogram writes a benchmark line with the average as "<unit>/op"
+// and percentiles as "<unit>/p50".
+//
+// Histograms without measurements, e.g. read from JSON, contain only
+// some of the percentiles, the other percentiles are skipped.
+//
+// Name is prefixed with "Benchmark", when necessary.
+func (writer *BenchWriter) WriteHistogram(name, unit string, hist *Histogram) error {
+	if hist.Count == 0 {
+		return fmt.Errorf("%s: no measurements", name)
+	}
+
+	var line strings.Builder
+	line.WriteString(benchName(name))
+	line.WriteString(" ")
+	line.WriteString(strconv.Itoa(hist.Count))
+	writeMetric(&line, hist.Average, unit+"/op")
+	for _, p := range writer.Percentiles {
+		value, ok := histogramPercentile(hist, p)
+		if !ok {
+			continue
+		}
+		q := Quantile{P: p, Value: value}
+		writeMetric(&line, q.Value, unit+"/"+q.Label())
+	}
+	line.WriteString("\n")
+
+	_, err := io.WriteString(writer.w, line.String())
+	return err
+}
+
+// WriteFloat64s writes a benchmark line from measurements in unit, e.g. "ns" or "tsc".
+func (writer *BenchWriter) WriteFloat64s(name, unit string, measurements []float64) error {
+	opts := defaultOptions
+	opts.BinCount = 1
+	return writer.WriteHistogram(name, unit, NewHistogram(measurements, &opts))
+}
+
+// WriteResult writes the environment of the result and a benchmark line.
+//
+// Measurements are written in the unit of the result, e.g. "tsc/op" for TSC results.
+func (writer *BenchWriter) WriteResult(result *Result) error {
+	if err := writer.Environment(result.Environment); err != nil {
+		return err
+	}
+	if len(result.Samples) > 0 || result.Histogram == nil {
+		return writer.WriteFloat64s(result.Name, result.Unit, result.Samples)
+	}
+	return writer.WriteHistogram(result.Name, "ns", result.Histogram)
+}
+
+// benchName converts name into a valid benchmark name.
+func benchName(name string) string {
+	name = strings.Join(strings.Fields(name), "_")
+	if name == "" {
+		return "Benchmark"
+	}
+	if !strings.HasPrefix(name, "Benchmark") {
+		name = "Benchmark" + strings.ToUpper(name[:1]) + name[1:]
+	}
+	return name
+}
+
+// histogramPercentile returns the percentile of hist. Histograms without
+// measurements, e.g. read from JSON, only contain the predefined percentiles
+// and it returns false for the other percentiles.
+func histogramPercentile(hist *Histogram, p float64) (float64, bool) {
+	if hist.dist != nil {
+		return hist.Percentile(p), true
+	}
+	switch p {
+	case 0.5:
+		return hist.P50, true
+	case 0.9:
+		return hist.P90, true
+	case 0.99:
+		return hist.P99, true
+	case 0.999:
+		return hist.P999, true
+	case 0.9999:
+		return hist.P9999, true
+	}
+	for _, q := range hist.Quantiles {
+		if q.P == p {
+			return q.Value, true
+		}
+	}
+	return 0, false
+}
+
+// writeMetric writes a single " value unit" pair.
+func writeMetric(line *strings.Builder, value float64, unit string) {
+	line.WriteString(" ")
+	line.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	line.WriteString(" ")
+	line.WriteString(unit)
+}
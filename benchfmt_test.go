@@ -0,0 +1,114 @@
+package hrtime_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleBenchWriter() {
+	bench := hrtime.NewBenchmark(4096)
+	for bench.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+
+	result := bench.Result()
+	result.Name = "Sleep"
+	result.Redact()
+
+	writer := hrtime.NewBenchWriter(os.Stdout)
+	_ = writer.WriteResult(result)
+}
+
+func TestBenchWriter(t *testing.T) {
+	var out strings.Builder
+	writer := hrtime.NewBenchWriter(&out)
+	writer.Percentiles = []float64{0.5, 0.999}
+
+	samples := []float64{}
+	for i := 1; i <= 1000; i++ {
+		samples = append(samples, float64(i))
+	}
+
+	if err := writer.Config("pkg", "example"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Config("pkg", "example"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteFloat64s("Linear", "ns", samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Config("pkg", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteFloat64s("BenchmarkCounts sub", "tsc", []float64{3}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"pkg: example\n" +
+		"BenchmarkLinear 1000 500.5 ns/op 501 ns/p50 1000 ns/p99.9\n" +
+		"pkg: other\n" +
+		"BenchmarkCounts_sub 1 3 tsc/op 3 tsc/p50 3 tsc/p99.9\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestBenchWriterConfig(t *testing.T) {
+	writer := hrtime.NewBenchWriter(&strings.Builder{})
+	for _, key := range []string{"", "Upper", "with space", "a:b"} {
+		if err := writer.Config(key, "x"); err == nil {
+			t.Errorf("expected error for %q", key)
+		}
+	}
+	if err := writer.Config("commit", "abc\ndef"); err == nil {
+		t.Errorf("expected error for multi-line value")
+	}
+	if err := writer.WriteFloat64s("Empty", "ns", nil); err == nil {
+		t.Errorf("expected error for no measurements")
+	}
+}
+
+func TestBenchWriterJSONHistogram(t *testing.T) {
+	input := `{"version": 1, "unit": "ns", "histogram": {"count": 10, "avg": 100, "p50": 90, "p90": 150, "p99": 200}}`
+	result, err := hrtime.ReadJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	writer := hrtime.NewBenchWriter(&out)
+	writer.Percentiles = []float64{0.5, 0.95}
+	if err := writer.WriteHistogram("JSON", "ns", result.Histogram); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "BenchmarkJSON 10 100 ns/op 90 ns/p50\n"; out.String() != expected {
+		t.Errorf("expected %q got %q", expected, out.String())
+	}
+}
+
+func TestBenchWriterResult(t *testing.T) {
+	bench := hrtime.NewBenchmarkTSC(16)
+	for bench.Next() {
+	}
+
+	result := bench.Result()
+	result.Name = "Empty"
+
+	var out strings.Builder
+	writer := hrtime.NewBenchWriter(&out)
+	if err := writer.WriteResult(result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "goos: ") {
+		t.Errorf("expected environment config:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "\nBenchmarkEmpty 16 ") || !strings.Contains(out.String(), " tsc/p99\n") {
+		t.Errorf("expected tsc benchmark line:\n%s", out.String())
+	}
+}
@@ -82,6 +82,15 @@ func (bench *Benchmark) Float64s() []float64 {
 	return measurements
 }
 
+// RecordTo adds all measured durations to rec.
+func (bench *Benchmark) RecordTo(rec Recorder) {
+	bench.mustBeCompleted()
+
+	for _, lap := range bench.laps {
+		rec.Record(float64(lap.Nanoseconds()))
+	}
+}
+
 // Histogram creates an histogram of all the laps.
 //
 // It creates binCount bins to distribute the data and uses the
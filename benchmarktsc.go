@@ -94,6 +94,15 @@ func (bench *BenchmarkTSC) Float64s() []float64 {
 	return measurements
 }
 
+// RecordTo adds all measured durations to rec using the approximate conversion of Count.
+func (bench *BenchmarkTSC) RecordTo(rec Recorder) {
+	bench.mustBeCompleted()
+
+	for _, count := range bench.counts {
+		rec.Record(float64(count.ApproxDuration().Nanoseconds()))
+	}
+}
+
 // Histogram creates an histogram of all the laps.
 //
 // It creates binCount bins to distribute the data and uses the
@@ -0,0 +1,365 @@
+package hrtime
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Measurements is a benchmark result that provides all measurements.
+//
+// Benchmark, BenchmarkTSC, Stopwatch, StopwatchTSC and Result implement Measurements.
+// When comparing, measurements in "tsc" unit are converted to nanoseconds
+// using the approximate conversion of Count.
+type Measurements interface {
+	Float64s() []float64
+}
+
+// CompareOptions is configuration for comparing measurements.
+type CompareOptions struct {
+	// Alpha is the significance level for the statistical tests.
+	Alpha float64
+	// Confidence is the confidence level of the bootstrap intervals.
+	Confidence float64
+	// Resamples is the number of bootstrap resamples.
+	Resamples int
+}
+
+var defaultCompareOptions = CompareOptions{
+	Alpha:      0.05,
+	Confidence: 0.95,
+	Resamples:  1000,
+}
+
+// Comparison is the statistical comparison of two results.
+type Comparison struct {
+	Old, New *Histogram
+
+	// Alpha is the significance level used for the tests.
+	Alpha float64
+
+	// MannWhitney tests whether either of the results tends to be larger.
+	MannWhitney TestResult
+	// KolmogorovSmirnov tests whether the results have a different distribution.
+	KolmogorovSmirnov TestResult
+	// EffectSize is Cliff's delta between -1 and 1, the probability
+	// that a new measurement is larger than an old one minus
+	// the probability that it's smaller.
+	EffectSize float64
+
+	// MedianDelta is the difference between the new and old median.
+	MedianDelta Delta
+	// P99Delta is the difference between the new and old 99th percentile.
+	P99Delta Delta
+}
+
+// TestResult is the result of a statistical test.
+type TestResult struct {
+	Statistic float64
+	PValue    float64
+}
+
+// Significant returns whether the result is significant at level alpha.
+func (result TestResult) Significant(alpha float64) bool {
+	return result.PValue < alpha
+}
+
+// Delta is the difference between new and old value of a statistic.
+type Delta struct {
+	// Estimate is the difference between the new and old value.
+	Estimate float64
+	// Relative is the difference relative to the old value.
+	Relative float64
+	// Interval is the bootstrap confidence interval of the difference.
+	Interval ConfidenceInterval
+}
+
+// Significant returns whether the confidence interval excludes zero.
+func (delta Delta) Significant() bool {
+	return !delta.Interval.Contains(0)
+}
+
+// Compare compares old measurements a to new measurements b with the default options.
+func Compare(a, b Measurements) *Comparison {
+	opts := defaultCompareOptions
+	return CompareWith(a, b, &opts)
+}
+
+// CompareWith compares old measurements a to new measurements b.
+//
+// It runs Mann-Whitney U and Kolmogorov-Smirnov tests and estimates
+// bootstrap confidence intervals for the difference of median and
+// 99th percentile.
+func CompareWith(a, b Measurements, opts *CompareOptions) *Comparison {
+	xs := measurementNanoseconds(a)
+	ys := measurementNanoseconds(b)
+	if len(xs) == 0 || len(ys) == 0 {
+		panic("must have at least 1 measurement")
+	}
+	sort.Float64s(xs)
+	sort.Float64s(ys)
+
+	histOpts := defaultOptions
+	cmp := &Comparison{
+		Old:   newHistogram(sortedSamples(xs), &histOpts),
+		New:   newHistogram(sortedSamples(ys), &histOpts),
+		Alpha: opts.Alpha,
+	}
+
+	var u float64
+	cmp.MannWhitney, u = mannWhitney(xs, ys)
+	cmp.EffectSize = 2*u/(float64(len(xs))*float64(len(ys))) - 1
+	cmp.KolmogorovSmirnov = kolmogorovSmirnov(xs, ys)
+
+	median := func(sorted []float64) float64 { return sortedSamples(sorted).quantile(0.5, Linear) }
+	p99 := func(sorted []float64) float64 { return sortedSamples(sorted).quantile(0.99, Linear) }
+	cmp.MedianDelta = bootstrapDelta(xs, ys, opts, median)
+	cmp.P99Delta = bootstrapDelta(xs, ys, opts, p99)
+
+	return cmp
+}
+
+// measurementNanoseconds returns a copy of the measurements in nanoseconds,
+// when the measurements are in "tsc" unit and the conversion is known.
+func measurementNanoseconds(m Measurements) []float64 {
+	nanos := append([]float64{}, m.Float64s()...)
+	switch m := m.(type) {
+	case *Result:
+		if converted, ok := m.nanoseconds(); ok {
+			return converted
+		}
+	case interface{ Unit() string }:
+		if perCount := approxNanosecondsPerCount(); m.Unit() == "tsc" && perCount > 0 {
+			for i := range nanos {
+				nanos[i] *= perCount
+			}
+		}
+	}
+	return nanos
+}
+
+// mannWhitney calculates Mann-Whitney U test for sorted xs and ys using
+// normal approximation. It also returns the U statistic of ys, which is
+// the number of pairs where y > x, counting ties as half.
+func mannWhitney(xs, ys []float64) (result TestResult, uy float64) {
+	nx, ny := float64(len(xs)), float64(len(ys))
+	n := nx + ny
+
+	// rank the merged samples, ties get the average rank
+	var rankSumY, tieCorrection float64
+	i, k := 0, 0
+	for i < len(xs) || k < len(ys) {
+		var v float64
+		if k >= len(ys) || (i < len(xs) && xs[i] <= ys[k]) {
+			v = xs[i]
+		} else {
+			v = ys[k]
+		}
+
+		countX, countY := 0, 0
+		for i < len(xs) && xs[i] == v {
+			countX++
+			i++
+		}
+		for k < len(ys) && ys[k] == v {
+			countY++
+			k++
+		}
+
+		first := float64(i + k - countX - countY + 1)
+		last := float64(i + k)
+		rankSumY += float64(countY) * (first + last) / 2
+
+		t := float64(countX + countY)
+		tieCorrection += t*t*t - t
+	}
+
+	uy = rankSumY - ny*(ny+1)/2
+	ux := nx*ny - uy
+	result.Statistic = math.Min(ux, uy)
+
+	mean := nx * ny / 2
+	variance := nx * ny / 12 * ((n + 1) - tieCorrection/(n*(n-1)))
+	if variance <= 0 {
+		result.PValue = 1
+		return result, uy
+	}
+
+	z := (math.Abs(uy-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	result.PValue = math.Erfc(z / math.Sqrt2)
+	return result, uy
+}
+
+// kolmogorovSmirnov calculates two-sample Kolmogorov-Smirnov test for sorted xs and ys.
+func kolmogorovSmirnov(xs, ys []float64) TestResult {
+	nx, ny := float64(len(xs)), float64(len(ys))
+
+	d := 0.0
+	i, k := 0, 0
+	for i < len(xs) && k < len(ys) {
+		v := math.Min(xs[i], ys[k])
+		for i < len(xs) && xs[i] == v {
+			i++
+		}
+		for k < len(ys) && ys[k] == v {
+			k++
+		}
+		d = math.Max(d, math.Abs(float64(i)/nx-float64(k)/ny))
+	}
+
+	en := math.Sqrt(nx * ny / (nx + ny))
+	lambda := (en + 0.12 + 0.11/en) * d
+	return TestResult{
+		Statistic: d,
+		PValue:    kolmogorovProbability(lambda),
+	}
+}
+
+// kolmogorovProbability returns the probability of Kolmogorov distribution exceeding lambda.
+func kolmogorovProbability(lambda float64) float64 {
+	if lambda < 0.2 {
+		return 1
+	}
+	sum := 0.0
+	sign := 1.0
+	for j := 1; j <= 100; j++ {
+		term := sign * 2 * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-10 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, sum))
+}
+
+// bootstrapDelta estimates the difference of statistic between sorted xs and ys.
+func bootstrapDelta(xs, ys []float64, opts *CompareOptions, statistic func(sorted []float64) float64) Delta {
+	before, after := statistic(xs), statistic(ys)
+	delta := Delta{
+		Estimate: after - before,
+		Interval: ConfidenceInterval{Confidence: opts.Confidence},
+	}
+	if before != 0 {
+		delta.Relative = (after - before) / before
+	}
+	if opts.Resamples <= 0 {
+		return delta
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	resample := func(dst, src []float64) []float64 {
+		for i := range dst {
+			dst[i] = src[rng.Intn(len(src))]
+		}
+		sort.Float64s(dst)
+		return dst
+	}
+
+	sampleX := make([]float64, len(xs))
+	sampleY := make([]float64, len(ys))
+	estimates := make([]float64, opts.Resamples)
+	for i := range estimates {
+		estimates[i] = statistic(resample(sampleY, ys)) - statistic(resample(sampleX, xs))
+	}
+	sort.Float64s(estimates)
+
+	alpha := (1 - opts.Confidence) / 2
+	delta.Interval.Lower = sortedSamples(estimates).quantile(alpha, Linear)
+	delta.Interval.Upper = sortedSamples(estimates).quantile(1-alpha, Linear)
+	return delta
+}
+
+// effectSizeLabel returns the conventional magnitude of Cliff's delta.
+func effectSizeLabel(delta float64) string {
+	switch d := math.Abs(delta); {
+	case d < 0.147:
+		return "negligible"
+	case d < 0.33:
+		return "small"
+	case d < 0.474:
+		return "medium"
+	default:
+		return "large"
+	}
+}
+
+// WriteTo writes side-by-side statistics and test results to w.
+func (cmp *Comparison) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	printf := func(format string, args ...interface{}) error {
+		n, err := fmt.Fprintf(w, format, args...)
+		written += int64(n)
+		return err
+	}
+	duration := func(v float64) time.Duration {
+		if v < 0 {
+			return -time.Duration(truncate(-v, 3))
+		}
+		return time.Duration(truncate(v, 3))
+	}
+	significance := func(significant bool) string {
+		if significant {
+			return "significant"
+		}
+		return "~"
+	}
+
+	if err := printf("  %-6s %10s %10s %9s\n", "", "old", "new", "delta"); err != nil {
+		return written, err
+	}
+
+	rows := []struct {
+		name     string
+		old, new float64
+		delta    *Delta
+	}{
+		{"avg", cmp.Old.Average, cmp.New.Average, nil},
+		{"min", cmp.Old.Minimum, cmp.New.Minimum, nil},
+		{"p50", cmp.Old.P50, cmp.New.P50, &cmp.MedianDelta},
+		{"p90", cmp.Old.P90, cmp.New.P90, nil},
+		{"p99", cmp.Old.P99, cmp.New.P99, &cmp.P99Delta},
+		{"max", cmp.Old.Maximum, cmp.New.Maximum, nil},
+	}
+	for _, row := range rows {
+		relative := 0.0
+		if row.old != 0 {
+			relative = (row.new - row.old) / row.old
+		}
+		if err := printf("  %-6s %10v %10v %+8.2f%%", row.name, duration(row.old), duration(row.new), relative*100); err != nil {
+			return written, err
+		}
+		if row.delta != nil {
+			err := printf("  [%v, %v] %s",
+				duration(row.delta.Interval.Lower), duration(row.delta.Interval.Upper),
+				significance(row.delta.Significant()))
+			if err != nil {
+				return written, err
+			}
+		}
+		if err := printf("\n"); err != nil {
+			return written, err
+		}
+	}
+
+	err := printf("  mann-whitney U=%v p=%.3g %s;  kolmogorov-smirnov D=%.3g p=%.3g %s;\n  effect size %+.3f %s;\n",
+		cmp.MannWhitney.Statistic, cmp.MannWhitney.PValue, significance(cmp.MannWhitney.Significant(cmp.Alpha)),
+		cmp.KolmogorovSmirnov.Statistic, cmp.KolmogorovSmirnov.PValue, significance(cmp.KolmogorovSmirnov.Significant(cmp.Alpha)),
+		cmp.EffectSize, effectSizeLabel(cmp.EffectSize),
+	)
+	return written, err
+}
+
+// String returns a string representation of the comparison.
+func (cmp *Comparison) String() string {
+	var buffer strings.Builder
+	_, _ = cmp.WriteTo(&buffer)
+	return buffer.String()
+}
@@ -0,0 +1,111 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+type float64s []float64
+
+func (xs float64s) Float64s() []float64 { return xs }
+
+func ExampleCompare() {
+	const numberOfExperiments = 4096
+	before := hrtime.NewBenchmark(numberOfExperiments)
+	for before.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+
+	after := hrtime.NewBenchmark(numberOfExperiments)
+	for after.Next() {
+		time.Sleep(2000 * time.Nanosecond)
+	}
+
+	fmt.Println(hrtime.Compare(before, after))
+}
+
+func normalSamples(seed int64, n int, mean, stddev float64) float64s {
+	rng := rand.New(rand.NewSource(seed))
+	xs := make(float64s, n)
+	for i := range xs {
+		xs[i] = mean + rng.NormFloat64()*stddev
+	}
+	return xs
+}
+
+func TestCompareTSC(t *testing.T) {
+	bench := hrtime.NewBenchmarkTSC(64)
+	for bench.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+
+	var maximum time.Duration
+	for _, lap := range bench.Laps() {
+		if lap > maximum {
+			maximum = lap
+		}
+	}
+
+	cmp := hrtime.Compare(bench, bench)
+	if math.Abs(cmp.Old.Maximum-float64(maximum)) > 0.01*float64(maximum) {
+		t.Errorf("expected maximum in nanoseconds %v got %v", maximum, cmp.Old.Maximum)
+	}
+}
+
+func TestCompareSame(t *testing.T) {
+	a := normalSamples(1, 500, 1000, 100)
+	b := normalSamples(2, 500, 1000, 100)
+
+	cmp := hrtime.CompareWith(a, b, &hrtime.CompareOptions{Alpha: 0.01, Confidence: 0.99, Resamples: 200})
+	if cmp.MannWhitney.Significant(0.01) {
+		t.Errorf("mann-whitney significant: %+v", cmp.MannWhitney)
+	}
+	if cmp.KolmogorovSmirnov.Significant(0.01) {
+		t.Errorf("kolmogorov-smirnov significant: %+v", cmp.KolmogorovSmirnov)
+	}
+	if cmp.MedianDelta.Significant() {
+		t.Errorf("median delta significant: %+v", cmp.MedianDelta)
+	}
+	if math.Abs(cmp.EffectSize) > 0.147 {
+		t.Errorf("effect size too large: %v", cmp.EffectSize)
+	}
+	t.Log(cmp)
+}
+
+func TestCompareShifted(t *testing.T) {
+	a := normalSamples(1, 500, 1000, 100)
+	b := normalSamples(2, 500, 1100, 100)
+
+	cmp := hrtime.CompareWith(a, b, &hrtime.CompareOptions{Alpha: 0.01, Confidence: 0.99, Resamples: 200})
+	if !cmp.MannWhitney.Significant(0.01) {
+		t.Errorf("mann-whitney not significant: %+v", cmp.MannWhitney)
+	}
+	if !cmp.KolmogorovSmirnov.Significant(0.01) {
+		t.Errorf("kolmogorov-smirnov not significant: %+v", cmp.KolmogorovSmirnov)
+	}
+	if !cmp.MedianDelta.Significant() || cmp.MedianDelta.Estimate <= 0 {
+		t.Errorf("median delta not significant: %+v", cmp.MedianDelta)
+	}
+	if cmp.EffectSize < 0.33 {
+		t.Errorf("effect size too small: %v", cmp.EffectSize)
+	}
+	t.Log(cmp)
+}
+
+func TestCompareTies(t *testing.T) {
+	a := float64s{1, 1, 1, 2, 2, 3}
+	b := float64s{1, 1, 1, 2, 2, 3}
+
+	cmp := hrtime.Compare(a, b)
+	if cmp.MannWhitney.Statistic != 18 || cmp.MannWhitney.PValue < 0.99 {
+		t.Errorf("invalid mann-whitney for identical samples: %+v", cmp.MannWhitney)
+	}
+	if cmp.KolmogorovSmirnov.Statistic != 0 || cmp.EffectSize != 0 {
+		t.Errorf("invalid result for identical samples: %+v %v", cmp.KolmogorovSmirnov, cmp.EffectSize)
+	}
+}
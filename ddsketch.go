@@ -0,0 +1,222 @@
+package hrtime
+
+import (
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// DDSketch is a mergeable streaming quantile sketch with
+// relative-error guarantees.
+//
+// Every percentile is within the configured relative error of the
+// exact value. Memory grows logarithmically with the range of values.
+//
+// DDSketch must not be used concurrently.
+type DDSketch struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+
+	// bins[i] contains count for index offset+i
+	offset    int
+	bins      []int64
+	zeroCount int64
+
+	total    int64
+	min, max float64
+	sum      float64
+}
+
+// NewDDSketch creates a new sketch with the specified relative accuracy, e.g. 0.01.
+func NewDDSketch(relativeAccuracy float64) *DDSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		panic("relative accuracy must be between 0 and 1")
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &DDSketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		min:              math.Inf(1),
+		max:              math.Inf(-1),
+	}
+}
+
+// index returns the bin index for a positive value.
+func (sketch *DDSketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / sketch.logGamma))
+}
+
+// value returns the representative value of the bin index.
+func (sketch *DDSketch) value(index int) float64 {
+	return 2 * math.Pow(sketch.gamma, float64(index)) / (sketch.gamma + 1)
+}
+
+// Record adds a measurement in nanoseconds.
+func (sketch *DDSketch) Record(ns float64) {
+	sketch.RecordN(ns, 1)
+}
+
+// RecordN adds a measurement in nanoseconds n times.
+func (sketch *DDSketch) RecordN(ns float64, n int64) {
+	if n <= 0 {
+		return
+	}
+
+	sketch.total += n
+	sketch.sum += ns * float64(n)
+	if ns < sketch.min {
+		sketch.min = ns
+	}
+	if ns > sketch.max {
+		sketch.max = ns
+	}
+
+	if ns < 1 {
+		// sub-nanosecond values are not distinguishable
+		sketch.zeroCount += n
+		return
+	}
+	sketch.add(sketch.index(ns), n)
+}
+
+// add adds n to the bin at index, growing bins as necessary.
+func (sketch *DDSketch) add(index int, n int64) {
+	if len(sketch.bins) == 0 {
+		sketch.offset = index
+		sketch.bins = make([]int64, 1)
+	}
+	if index < sketch.offset {
+		grow := sketch.offset - index
+		bins := make([]int64, grow+len(sketch.bins))
+		copy(bins[grow:], sketch.bins)
+		sketch.bins = bins
+		sketch.offset = index
+	}
+	if i := index - sketch.offset; i >= len(sketch.bins) {
+		sketch.bins = append(sketch.bins, make([]int64, i-len(sketch.bins)+1)...)
+	}
+	sketch.bins[index-sketch.offset] += n
+}
+
+// RecordDuration adds a measurement.
+func (sketch *DDSketch) RecordDuration(d time.Duration) {
+	sketch.Record(float64(d))
+}
+
+// Merge adds all measurements from other to sketch.
+//
+// When the sketches have different accuracy, the result
+// has the combined error of both.
+func (sketch *DDSketch) Merge(other *DDSketch) {
+	if other.total == 0 {
+		return
+	}
+
+	sameLayout := sketch.gamma == other.gamma
+	for i, count := range other.bins {
+		if count == 0 {
+			continue
+		}
+		if sameLayout {
+			sketch.add(other.offset+i, count)
+		} else {
+			sketch.add(sketch.index(other.value(other.offset+i)), count)
+		}
+	}
+	sketch.zeroCount += other.zeroCount
+	sketch.total += other.total
+	sketch.sum += other.sum
+	sketch.min = math.Min(sketch.min, other.min)
+	sketch.max = math.Max(sketch.max, other.max)
+}
+
+// clone returns a copy of the sketch.
+func (sketch *DDSketch) clone() *DDSketch {
+	clone := *sketch
+	clone.bins = append(sketch.bins[:0:0], sketch.bins...)
+	return &clone
+}
+
+// Count returns the number of measurements.
+func (sketch *DDSketch) Count() int64 { return sketch.total }
+
+// Percentile returns the approximate value at the specified percentile, e.g. 0.99.
+func (sketch *DDSketch) Percentile(p float64) float64 {
+	if sketch.total == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return sketch.min
+	}
+	if p >= 1 {
+		return sketch.max
+	}
+
+	rank := p * float64(sketch.total-1)
+	cumulative := float64(sketch.zeroCount)
+	if cumulative > rank {
+		return sketch.min
+	}
+	for i, count := range sketch.bins {
+		cumulative += float64(count)
+		if cumulative > rank {
+			return sketch.clamp(sketch.value(sketch.offset + i))
+		}
+	}
+	return sketch.max
+}
+
+// clamp clamps the value to the recorded range.
+func (sketch *DDSketch) clamp(v float64) float64 {
+	return math.Max(sketch.min, math.Min(sketch.max, v))
+}
+
+// RelativeError returns the maximum relative error of percentiles.
+func (sketch *DDSketch) RelativeError() float64 { return sketch.relativeAccuracy }
+
+func (sketch *DDSketch) count() int       { return int(sketch.total) }
+func (sketch *DDSketch) minimum() float64 { return sketch.min }
+func (sketch *DDSketch) maximum() float64 { return sketch.max }
+func (sketch *DDSketch) mean() float64    { return sketch.sum / float64(sketch.total) }
+
+func (sketch *DDSketch) quantile(p float64, _ Interpolation) float64 { return sketch.Percentile(p) }
+
+func (sketch *DDSketch) each(fn func(value float64, count int)) {
+	if sketch.zeroCount > 0 {
+		fn(sketch.min, int(sketch.zeroCount))
+	}
+	for i, count := range sketch.bins {
+		if count > 0 {
+			fn(sketch.clamp(sketch.value(sketch.offset+i)), int(count))
+		}
+	}
+}
+
+// Histogram converts the sketch into a Histogram.
+func (sketch *DDSketch) Histogram(opts *HistogramOptions) *Histogram {
+	result := newHistogram(sketch.clone(), opts)
+	result.RelativeError = sketch.RelativeError()
+	return result
+}
+
+// WriteStatsTo writes formatted statistics to w.
+func (sketch *DDSketch) WriteStatsTo(w io.Writer) (int64, error) {
+	opts := defaultOptions
+	return sketch.Histogram(&opts).WriteStatsTo(w)
+}
+
+// WriteTo writes formatted statistics and histogram to w.
+func (sketch *DDSketch) WriteTo(w io.Writer) (int64, error) {
+	opts := defaultOptions
+	return sketch.Histogram(&opts).WriteTo(w)
+}
+
+// String returns a string representation of the sketch.
+func (sketch *DDSketch) String() string {
+	var buffer strings.Builder
+	_, _ = sketch.WriteTo(&buffer)
+	return buffer.String()
+}
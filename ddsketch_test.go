@@ -0,0 +1,59 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleDDSketch() {
+	const numberOfExperiments = 4096
+	bench := hrtime.NewStopwatch(numberOfExperiments)
+	for i := 0; i < numberOfExperiments; i++ {
+		go func() {
+			lap := bench.Start()
+			defer bench.Stop(lap)
+
+			time.Sleep(1000 * time.Nanosecond)
+		}()
+	}
+	bench.Wait()
+
+	sketch := hrtime.NewDDSketch(0.01)
+	bench.RecordTo(sketch)
+	fmt.Println(sketch)
+}
+
+func TestDDSketch(t *testing.T) {
+	values, sorted := lognormal(100000)
+
+	a, b := hrtime.NewDDSketch(0.01), hrtime.NewDDSketch(0.01)
+	for i, v := range values {
+		if i%2 == 0 {
+			a.Record(v)
+		} else {
+			b.Record(v)
+		}
+	}
+	a.Merge(b)
+
+	if a.Count() != int64(len(values)) {
+		t.Fatalf("invalid count %v", a.Count())
+	}
+	for _, p := range []float64{0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+		exp := sorted[int(p*float64(len(sorted)-1))]
+		got := a.Percentile(p)
+		if math.Abs(got-exp)/exp > a.RelativeError() {
+			t.Errorf("p%v: got %v expected %v", p*100, got, exp)
+		}
+	}
+
+	hist := a.Histogram(&hrtime.HistogramOptions{BinCount: 10, NiceRange: true, ClampPercentile: 0.99})
+	if hist.RelativeError != 0.01 {
+		t.Errorf("missing relative error")
+	}
+	t.Log(hist)
+}
@@ -0,0 +1,192 @@
+package hrtime
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Env describes the environment where the measurements were made.
+type Env struct {
+	// CPU is the processor model name.
+	CPU string
+	// NumCPU is the number of logical processors.
+	NumCPU int
+	// GOMAXPROCS is the number of processors Go is allowed to use.
+	GOMAXPROCS int
+
+	GoVersion string
+	GOOS      string
+	GOARCH    string
+
+	// Clock is the clock used by Now.
+	Clock string
+	// ClockSource is the kernel clock source, e.g. "tsc" or "hpet".
+	ClockSource string
+	// Governor is the CPU frequency scaling governor, e.g. "performance".
+	Governor string
+	// FrequencyMHz is the current frequency of the processor.
+	FrequencyMHz float64
+
+	// TSCInvariant is whether the time stamp counter runs at a constant rate.
+	TSCInvariant bool
+	// NowOverhead is the overhead of calling Now.
+	NowOverhead time.Duration
+	// TSCOverhead is the overhead of calling TSC.
+	TSCOverhead Count
+
+	// Hostname is the name of the machine, it can be removed with Redact.
+	Hostname string
+}
+
+var (
+	environmentOnce sync.Once
+	environment     Env
+)
+
+// Environment returns information about the current environment.
+//
+// The information is collected on the first call,
+// however GOMAXPROCS is updated on every call.
+func Environment() *Env {
+	environmentOnce.Do(func() {
+		environment = Env{
+			CPU:       cpuModel(),
+			NumCPU:    runtime.NumCPU(),
+			GoVersion: runtime.Version(),
+			GOOS:      runtime.GOOS,
+			GOARCH:    runtime.GOARCH,
+
+			Clock: clockSource,
+
+			TSCInvariant: TSCSupported(),
+			NowOverhead:  Overhead(),
+			TSCOverhead:  TSCOverhead(),
+		}
+		environment.Hostname, _ = os.Hostname()
+		readSystemEnvironment(&environment)
+	})
+
+	env := environment
+	env.GOMAXPROCS = runtime.GOMAXPROCS(0)
+	return &env
+}
+
+// cpuModel returns the processor brand string using cpuid
+// or the operating system, when cpuid is not available.
+func cpuModel() string {
+	maxLeaf, _, _, _ := cpuid(0x80000000, 0)
+	if maxLeaf < 0x80000004 {
+		return readCPUModel()
+	}
+
+	var brand [48]byte
+	for i := uint32(0); i < 3; i++ {
+		eax, ebx, ecx, edx := cpuid(0x80000002+i, 0)
+		for k, reg := range []uint32{eax, ebx, ecx, edx} {
+			binary.LittleEndian.PutUint32(brand[i*16+uint32(k)*4:], reg)
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(string(brand[:]), "\x00"))
+}
+
+// Redact removes identifying information from env.
+func (env *Env) Redact() {
+	env.Hostname = ""
+}
+
+// environmentKeys is the order of the environment values in reports.
+var environmentKeys = []string{
+	"cpu", "cpus", "gomaxprocs", "go", "goos", "goarch",
+	"clock", "clocksource", "governor", "cpufreq",
+	"tsc-invariant", "now-overhead", "tsc-overhead", "hostname",
+}
+
+// Map returns the non-empty environment values as key-value pairs, e.g. "goos": "linux".
+func (env *Env) Map() map[string]string {
+	m := map[string]string{
+		"cpu":           env.CPU,
+		"cpus":          strconv.Itoa(env.NumCPU),
+		"gomaxprocs":    strconv.Itoa(env.GOMAXPROCS),
+		"go":            env.GoVersion,
+		"goos":          env.GOOS,
+		"goarch":        env.GOARCH,
+		"clock":         env.Clock,
+		"clocksource":   env.ClockSource,
+		"governor":      env.Governor,
+		"tsc-invariant": strconv.FormatBool(env.TSCInvariant),
+		"now-overhead":  env.NowOverhead.String(),
+		"tsc-overhead":  strconv.FormatInt(int64(env.TSCOverhead), 10),
+		"hostname":      env.Hostname,
+	}
+	if env.FrequencyMHz > 0 {
+		m["cpufreq"] = strconv.FormatFloat(env.FrequencyMHz, 'f', -1, 64) + "MHz"
+	}
+	for key, value := range m {
+		if value == "" {
+			delete(m, key)
+		}
+	}
+	return m
+}
+
+// WriteTo writes the environment as "key: value" lines to w.
+func (env *Env) WriteTo(w io.Writer) (int64, error) {
+	return writeEnvironment(w, env.Map())
+}
+
+// writeEnvironment writes "key: value" lines to w in the order of environmentOrder.
+func writeEnvironment(w io.Writer, env map[string]string) (int64, error) {
+	var written int64
+	for _, key := range environmentOrder(env) {
+		n, err := fmt.Fprintf(w, "%s: %s\n", key, env[key])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// environmentOrder returns the keys of env in the order of
+// environmentKeys followed by the other keys in alphabetical order.
+func environmentOrder(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for _, key := range environmentKeys {
+		if _, ok := env[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	known := len(keys)
+	for key := range env {
+		if !containsString(environmentKeys, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys[known:])
+	return keys
+}
+
+// containsString returns whether xs contains x.
+func containsString(xs []string, x string) bool {
+	for _, v := range xs {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the environment as "key: value" lines.
+func (env *Env) String() string {
+	var buffer strings.Builder
+	_, _ = env.WriteTo(&buffer)
+	return buffer.String()
+}
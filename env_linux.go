@@ -0,0 +1,59 @@
+// +build linux
+
+package hrtime
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readSystemEnvironment reads clock and frequency information from sysfs.
+func readSystemEnvironment(env *Env) {
+	env.ClockSource = readSysfs("/sys/devices/system/clocksource/clocksource0/current_clocksource")
+	env.Governor = readSysfs("/sys/devices/system/cpu/cpu0/cpufreq/scaling_governor")
+
+	if khz, err := strconv.ParseFloat(readSysfs("/sys/devices/system/cpu/cpu0/cpufreq/scaling_cur_freq"), 64); err == nil {
+		env.FrequencyMHz = khz / 1000
+	} else if mhz, err := strconv.ParseFloat(readCPUInfo("cpu MHz"), 64); err == nil {
+		env.FrequencyMHz = mhz
+	}
+}
+
+// readCPUModel reads the processor model name from /proc/cpuinfo.
+func readCPUModel() string {
+	return readCPUInfo("model name")
+}
+
+// readSysfs reads a single value file, it returns "" when the file is not available.
+func readSysfs(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readCPUInfo returns the first value of key in /proc/cpuinfo.
+func readCPUInfo(key string) string {
+	file, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		if strings.TrimSpace(line[:colon]) == key {
+			return strings.TrimSpace(line[colon+1:])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package hrtime
+
+// readSystemEnvironment is not supported on this platform.
+func readSystemEnvironment(env *Env) {}
+
+// readCPUModel is not supported on this platform.
+func readCPUModel() string { return "" }
@@ -0,0 +1,76 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleEnvironment() {
+	env := hrtime.Environment()
+	env.Redact()
+	fmt.Print(env)
+}
+
+func TestEnvironment(t *testing.T) {
+	env := hrtime.Environment()
+	if env.GOOS != runtime.GOOS || env.GOARCH != runtime.GOARCH || env.GoVersion != runtime.Version() {
+		t.Errorf("unexpected platform %+v", env)
+	}
+	if env.NumCPU != runtime.NumCPU() || env.GOMAXPROCS != runtime.GOMAXPROCS(0) {
+		t.Errorf("unexpected processor count %+v", env)
+	}
+	if env.TSCInvariant != hrtime.TSCSupported() {
+		t.Errorf("unexpected tsc invariance")
+	}
+
+	m := env.Map()
+	if m["goos"] != runtime.GOOS || m["gomaxprocs"] == "" {
+		t.Errorf("unexpected map %v", m)
+	}
+
+	env.Hostname = "secret-host"
+	if !strings.Contains(env.String(), "hostname: secret-host\n") {
+		t.Errorf("expected hostname:\n%v", env)
+	}
+	env.Redact()
+	if strings.Contains(env.String(), "secret-host") {
+		t.Errorf("expected hostname to be redacted:\n%v", env)
+	}
+	if hrtime.Environment().Hostname == "secret-host" {
+		t.Errorf("modifying the result must not affect Environment")
+	}
+}
+
+func TestEnvironmentResult(t *testing.T) {
+	bench := hrtime.NewBenchmark(8)
+	for bench.Next() {
+	}
+
+	result := bench.Result()
+	if result.Environment["goarch"] != runtime.GOARCH {
+		t.Errorf("expected environment to be attached: %v", result.Environment)
+	}
+
+	result.Name = "Empty"
+	result.Environment["hostname"] = "secret-host"
+	result.Environment["zcustom"] = "value"
+	result.Redact()
+
+	report := result.String()
+	if !strings.HasPrefix(report, "name: Empty\n") {
+		t.Errorf("expected name in header:\n%v", report)
+	}
+	if strings.Contains(report, "secret-host") {
+		t.Errorf("expected hostname to be redacted:\n%v", report)
+	}
+	goos := strings.Index(report, "goos: ")
+	custom := strings.Index(report, "zcustom: value")
+	stats := strings.Index(report, "avg ")
+	if goos < 0 || custom < goos || stats < custom {
+		t.Errorf("expected environment before statistics:\n%v", report)
+	}
+}
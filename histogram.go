@@ -1,6 +1,8 @@
 package hrtime
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -11,12 +13,24 @@ import (
 
 // HistogramOptions is configuration.
 type HistogramOptions struct {
-	BinCount int
+	BinCount int `json:"binCount"`
 	// NiceRange will try to round the bucket sizes to have a nicer output.
-	NiceRange bool
+	NiceRange bool `json:"niceRange"`
 	// Clamp values to either percentile or to a specific ns value.
-	ClampMaximum    float64
-	ClampPercentile float64
+	ClampMaximum    float64 `json:"clampMaximum"`
+	ClampPercentile float64 `json:"clampPercentile"`
+	// Percentiles are additional percentiles to calculate and print, e.g. 0.95 or 0.995.
+	Percentiles []float64 `json:"percentiles,omitempty"`
+	// Interpolation defines how percentiles are calculated from samples.
+	Interpolation Interpolation `json:"interpolation"`
+	// Layout defines how the bins are spaced.
+	Layout BinLayout `json:"layout"`
+	// Edges defines explicit bin starts in ascending order.
+	// When specified, Layout and BinCount are ignored.
+	// The first bin starts at the minimum, when it is below the first edge.
+	Edges []float64 `json:"edges,omitempty"`
+	// ShowDispersion calculates dispersion statistics and includes them in WriteStatsTo.
+	ShowDispersion bool `json:"showDispersion,omitempty"`
 }
 
 var defaultOptions = HistogramOptions{
@@ -28,16 +42,55 @@ var defaultOptions = HistogramOptions{
 
 // Histogram is a binned historgram with different statistics.
 type Histogram struct {
-	Minimum float64
-	Average float64
-	Maximum float64
-
-	P50, P90, P99, P999, P9999 float64
-
-	Bins []HistogramBin
+	// Count is the number of measurements.
+	Count int `json:"count"`
+
+	Minimum float64 `json:"min"`
+	Average float64 `json:"avg"`
+	Maximum float64 `json:"max"`
+
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p999"`
+	P9999 float64 `json:"p9999"`
+
+	// RelativeError is the maximum relative error of the statistics,
+	// when they are calculated from an approximation.
+	RelativeError float64 `json:"relativeError,omitempty"`
+	// RankError is the approximate maximum rank error of the percentiles,
+	// when they are calculated from an approximation.
+	RankError float64 `json:"rankError,omitempty"`
+
+	// Quantiles contains the additional percentiles from HistogramOptions.
+	Quantiles []Quantile `json:"quantiles,omitempty"`
+
+	// Dispersion statistics are only calculated with HistogramOptions.ShowDispersion.
+
+	// StdDev is the sample standard deviation, with n-1 in the denominator.
+	StdDev float64 `json:"stddev,omitempty"`
+	// MAD is the median absolute deviation.
+	MAD float64 `json:"mad,omitempty"`
+	// IQR is the interquartile range, the difference between p75 and p25.
+	IQR float64 `json:"iqr,omitempty"`
+	// Skewness is the adjusted Fisher-Pearson sample skewness (G1).
+	Skewness float64 `json:"skewness,omitempty"`
+	// Kurtosis is the sample excess kurtosis (G2).
+	Kurtosis float64 `json:"kurtosis,omitempty"`
+
+	Bins []HistogramBin `json:"bins"`
 
 	// for pretty printing
-	Width int
+	Width int `json:"-"`
+
+	// dist is the distribution the histogram was created from.
+	dist distribution
+	// interpolation is used for calculating percentiles from dist.
+	interpolation Interpolation
+	// divisor is the total amount the histogram has been divided by.
+	divisor float64
+	// opts are the options the histogram was created with.
+	opts HistogramOptions
 }
 
 // HistogramBin is a single bin in histogram
@@ -48,6 +101,39 @@ type HistogramBin struct {
 	andAbove bool
 }
 
+// histogramBinJSON is the serialized form of HistogramBin.
+type histogramBinJSON struct {
+	Start    float64 `json:"start"`
+	Count    int     `json:"count"`
+	Width    float64 `json:"width"`
+	AndAbove bool    `json:"andAbove,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (bin HistogramBin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(histogramBinJSON{
+		Start:    bin.Start,
+		Count:    bin.Count,
+		Width:    bin.Width,
+		AndAbove: bin.andAbove,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (bin *HistogramBin) UnmarshalJSON(data []byte) error {
+	var v histogramBinJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*bin = HistogramBin{
+		Start:    v.Start,
+		Count:    v.Count,
+		Width:    v.Width,
+		andAbove: v.AndAbove,
+	}
+	return nil
+}
+
 // NewDurationHistogram creates a histogram from time.Duration-s.
 func NewDurationHistogram(durations []time.Duration, opts *HistogramOptions) *Histogram {
 	nanos := make([]float64, len(durations))
@@ -59,41 +145,121 @@ func NewDurationHistogram(durations []time.Duration, opts *HistogramOptions) *Hi
 
 // NewHistogram creates a new histogram from the specified nanosecond values.
 func NewHistogram(nanoseconds []float64, opts *HistogramOptions) *Histogram {
-	if opts.BinCount <= 0 {
-		panic("binCount must be larger than 0")
-	}
-
-	hist := &Histogram{}
-	hist.Width = 40
-	hist.Bins = make([]HistogramBin, opts.BinCount)
-	if len(nanoseconds) == 0 {
-		return hist
-	}
-
 	nanoseconds = append(nanoseconds[:0:0], nanoseconds...)
 	sort.Float64s(nanoseconds)
+	return newHistogram(sortedSamples(nanoseconds), opts)
+}
+
+// distribution is a set of measurements that can be binned.
+type distribution interface {
+	// count returns the number of measurements.
+	count() int
+	// minimum returns the smallest measurement.
+	minimum() float64
+	// maximum returns the largest measurement.
+	maximum() float64
+	// mean returns the average of measurements.
+	mean() float64
+	// quantile returns the value at the specified quantile.
+	// Approximate distributions may ignore the interpolation method.
+	quantile(p float64, method Interpolation) float64
+	// each calls fn for each distinct value in ascending order.
+	each(fn func(value float64, count int))
+}
+
+// sortedSamples implements distribution for sorted measurements.
+type sortedSamples []float64
 
-	hist.Minimum = nanoseconds[0]
-	hist.Maximum = nanoseconds[len(nanoseconds)-1]
+func (xs sortedSamples) count() int       { return len(xs) }
+func (xs sortedSamples) minimum() float64 { return xs[0] }
+func (xs sortedSamples) maximum() float64 { return xs[len(xs)-1] }
 
-	hist.Average = float64(0)
-	for _, x := range nanoseconds {
-		hist.Average += x
+func (xs sortedSamples) mean() float64 {
+	total := float64(0)
+	for _, x := range xs {
+		total += x
 	}
-	hist.Average /= float64(len(nanoseconds))
+	return total / float64(len(xs))
+}
 
-	p := func(p float64) float64 {
-		i := int(math.Round(p * float64(len(nanoseconds))))
-		if i < 0 {
-			i = 0
+func (xs sortedSamples) quantile(p float64, method Interpolation) float64 {
+	if method == Linear {
+		if p <= 0 {
+			return xs[0]
+		}
+		if p >= 1 {
+			return xs[len(xs)-1]
 		}
-		if i >= len(nanoseconds) {
-			i = len(nanoseconds) - 1
+		pos := p * float64(len(xs)-1)
+		i := int(pos)
+		frac := pos - float64(i)
+		if i+1 >= len(xs) {
+			return xs[i]
 		}
-		return nanoseconds[i]
+		return xs[i] + (xs[i+1]-xs[i])*frac
+	}
+
+	i := int(math.Round(p * float64(len(xs))))
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(xs) {
+		i = len(xs) - 1
 	}
+	return xs[i]
+}
 
+func (xs sortedSamples) each(fn func(value float64, count int)) {
+	for _, x := range xs {
+		fn(x, 1)
+	}
+}
+
+// validate checks whether a histogram can be created using opts.
+func (opts *HistogramOptions) validate() error {
+	if opts.BinCount <= 0 && len(opts.Edges) == 0 {
+		return errors.New("binCount must be larger than 0")
+	}
+	if !sort.Float64sAreSorted(opts.Edges) {
+		return errors.New("edges must be in ascending order")
+	}
+	return nil
+}
+
+// newHistogram creates a new histogram from the distribution.
+func newHistogram(dist distribution, opts *HistogramOptions) *Histogram {
+	if err := opts.validate(); err != nil {
+		panic(err.Error())
+	}
+	binCount := opts.BinCount
+	if len(opts.Edges) > 0 {
+		binCount = len(opts.Edges)
+	}
+
+	hist := &Histogram{}
+	hist.Width = 40
+	hist.Bins = make([]HistogramBin, binCount)
+	hist.interpolation = opts.Interpolation
+	hist.divisor = 1
+	hist.opts = *opts
+	if dist.count() == 0 {
+		return hist
+	}
+	hist.dist = dist
+	hist.Count = dist.count()
+
+	hist.Minimum = dist.minimum()
+	hist.Maximum = dist.maximum()
+	hist.Average = dist.mean()
+
+	p := hist.Percentile
 	hist.P50, hist.P90, hist.P99, hist.P999, hist.P9999 = p(0.50), p(0.90), p(0.99), p(0.999), p(0.9999)
+	for _, q := range opts.Percentiles {
+		hist.Quantiles = append(hist.Quantiles, Quantile{P: q, Value: p(q)})
+	}
+	if opts.ShowDispersion {
+		hist.calculateDispersion()
+	}
 
 	clampMaximum := hist.Maximum
 	if opts.ClampPercentile > 0 {
@@ -103,44 +269,73 @@ func NewHistogram(nanoseconds []float64, opts *HistogramOptions) *Histogram {
 		clampMaximum = opts.ClampMaximum
 	}
 
-	var minimum, spacing float64
+	switch {
+	case len(opts.Edges) > 0:
+		for i, edge := range opts.Edges {
+			hist.Bins[i].Start = edge
+		}
+		// values below the first edge are counted into the first bin
+		hist.Bins[0].Start = math.Min(hist.Minimum, opts.Edges[0])
+		fillBins(hist.Bins, dist, 1, math.Inf(1))
+		hist.Bins[len(hist.Bins)-1].andAbove = hist.Bins[len(hist.Bins)-1].Count > 0
+	case opts.Layout == LogBins:
+		upper := logBinStarts(hist.Bins, hist.Minimum, clampMaximum, opts.NiceRange)
+		fillBins(hist.Bins, dist, 1, upper)
+	case opts.Layout == PercentileBins:
+		for i := range hist.Bins {
+			hist.Bins[i].Start = p(float64(i) / float64(len(hist.Bins)))
+		}
+		fillBins(hist.Bins, dist, 1, math.Inf(1))
+	default:
+		var minimum, spacing float64
 
-	if opts.NiceRange {
-		minimum, spacing = calculateNiceSteps(hist.Minimum, clampMaximum, opts.BinCount)
-	} else {
-		minimum, spacing = calculateSteps(hist.Minimum, clampMaximum, opts.BinCount)
-	}
+		if opts.NiceRange {
+			minimum, spacing = calculateNiceSteps(hist.Minimum, clampMaximum, opts.BinCount)
+		} else {
+			minimum, spacing = calculateSteps(hist.Minimum, clampMaximum, opts.BinCount)
+		}
 
-	for i := range hist.Bins {
-		hist.Bins[i].Start = spacing*float64(i) + minimum
+		for i := range hist.Bins {
+			hist.Bins[i].Start = spacing*float64(i) + minimum
+		}
+		hist.Bins[0].Start = hist.Minimum
+
+		fillLinearBins(hist.Bins, dist, 1, minimum, spacing)
 	}
-	hist.Bins[0].Start = hist.Minimum
+	normalizeBins(hist.Bins)
 
-	for _, x := range nanoseconds {
-		k := int(float64(x-minimum) / spacing)
+	return hist
+}
+
+// fillLinearBins counts values of dist divided by divisor into
+// bins starting at minimum with the specified spacing.
+func fillLinearBins(bins []HistogramBin, dist distribution, divisor, minimum, spacing float64) {
+	dist.each(func(x float64, count int) {
+		k := int((x/divisor - minimum) / spacing)
 		if k < 0 {
 			k = 0
 		}
-		if k >= opts.BinCount {
-			k = opts.BinCount - 1
-			hist.Bins[k].andAbove = true
+		if k >= len(bins) {
+			k = len(bins) - 1
+			bins[k].andAbove = true
 		}
-		hist.Bins[k].Count++
-	}
+		bins[k].Count += count
+	})
+}
 
+// normalizeBins calculates the relative width of each bin.
+func normalizeBins(bins []HistogramBin) {
 	maxBin := 0
-	for _, bin := range hist.Bins {
+	for _, bin := range bins {
 		if bin.Count > maxBin {
 			maxBin = bin.Count
 		}
 	}
 
-	for k := range hist.Bins {
-		bin := &hist.Bins[k]
+	for k := range bins {
+		bin := &bins[k]
 		bin.Width = float64(bin.Count) / float64(maxBin)
 	}
-
-	return hist
 }
 
 // Divide divides histogram by number of repetitions for the tests.
@@ -155,11 +350,33 @@ func (hist *Histogram) Divide(n int) {
 	hist.P999 /= float64(n)
 	hist.P9999 /= float64(n)
 
+	for i := range hist.Quantiles {
+		hist.Quantiles[i].Value /= float64(n)
+	}
+
+	hist.StdDev /= float64(n)
+	hist.MAD /= float64(n)
+	hist.IQR /= float64(n)
+
+	if hist.divisor == 0 {
+		hist.divisor = 1
+	}
+	hist.divisor *= float64(n)
+
 	for i := range hist.Bins {
 		hist.Bins[i].Start /= float64(n)
 	}
 }
 
+// Divisor returns the total amount the histogram has been divided by,
+// or 1 when Divide hasn't been called.
+func (hist *Histogram) Divisor() float64 {
+	if hist.divisor == 0 {
+		return 1
+	}
+	return hist.divisor
+}
+
 // WriteStatsTo writes formatted statistics to w.
 func (hist *Histogram) WriteStatsTo(w io.Writer) (int64, error) {
 	n, err := fmt.Fprintf(w, "  avg %v;  min %v;  p50 %v;  max %v;\n  p90 %v;  p99 %v;  p999 %v;  p9999 %v;\n",
@@ -173,7 +390,67 @@ func (hist *Histogram) WriteStatsTo(w io.Writer) (int64, error) {
 		time.Duration(truncate(hist.P999, 3)),
 		time.Duration(truncate(hist.P9999, 3)),
 	)
-	return int64(n), err
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	if hist.opts.ShowDispersion {
+		n, err = fmt.Fprintf(w, "  stddev %v;  cv %v%%;  mad %v;  iqr %v;\n  skewness %.3g;  kurtosis %.3g;\n",
+			time.Duration(truncate(hist.StdDev, 3)),
+			round(hist.CV()*100, 3),
+			time.Duration(truncate(hist.MAD, 3)),
+			time.Duration(truncate(hist.IQR, 3)),
+			hist.Skewness,
+			hist.Kurtosis,
+		)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if len(hist.Quantiles) > 0 {
+		for _, q := range hist.Quantiles {
+			n, err = fmt.Fprintf(w, "  %v %v;", q.Label(), time.Duration(truncate(q.Value, 3)))
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+		n, err = io.WriteString(w, "\n")
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if hist.RelativeError == 0 && hist.RankError == 0 {
+		return written, nil
+	}
+
+	n, err = io.WriteString(w, " ")
+	written += int64(n)
+	if err != nil {
+		return written, err
+	}
+	if hist.RelativeError > 0 {
+		n, err = fmt.Fprintf(w, " relative error ±%v%%;", round(hist.RelativeError*100, 2))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	if hist.RankError > 0 {
+		n, err = fmt.Fprintf(w, " rank error ±%v%%;", round(hist.RankError*100, 2))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	n, err = io.WriteString(w, "\n")
+	written += int64(n)
+	return written, err
 }
 
 // WriteTo writes formatted statistics and histogram to w.
@@ -192,12 +469,14 @@ func (hist *Histogram) WriteTo(w io.Writer) (int64, error) {
 		}
 	}
 
+	digits := binLabelDigits(hist.Bins)
+
 	var n int
 	for _, bin := range hist.Bins {
 		if bin.andAbove {
-			n, err = fmt.Fprintf(w, " %10v+[%[2]*[3]v] ", time.Duration(round(bin.Start, 3)), maxCountLength, bin.Count)
+			n, err = fmt.Fprintf(w, " %10v+[%[2]*[3]v] ", time.Duration(round(bin.Start, digits)), maxCountLength, bin.Count)
 		} else {
-			n, err = fmt.Fprintf(w, " %10v [%[2]*[3]v] ", time.Duration(round(bin.Start, 3)), maxCountLength, bin.Count)
+			n, err = fmt.Fprintf(w, " %10v [%[2]*[3]v] ", time.Duration(round(bin.Start, digits)), maxCountLength, bin.Count)
 		}
 
 		written += int64(n)
@@ -3,12 +3,19 @@ package hrtime
 import "math"
 
 func calculateSteps(min, max float64, bincount int) (minimum, spacing float64) {
+	if max <= min {
+		// all values are equal, any positive spacing works
+		return min, 1
+	}
 	minimum = min
 	spacing = (max - min) / float64(bincount)
 	return minimum, spacing
 }
 
 func calculateNiceSteps(min, max float64, bincount int) (minimum, spacing float64) {
+	if max <= min {
+		return min, 1
+	}
 	span := niceNumber(max-min, false)
 	spacing = niceNumber(span/float64(bincount-1), true)
 	minimum = math.Floor(min/spacing) * spacing
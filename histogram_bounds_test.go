@@ -30,3 +30,12 @@ func TestTruncate(t *testing.T) {
 		}
 	}
 }
+
+func TestStepsEqualValues(t *testing.T) {
+	for _, steps := range []func(min, max float64, bincount int) (float64, float64){calculateSteps, calculateNiceSteps} {
+		minimum, spacing := steps(5e6, 5e6, 10)
+		if minimum != 5e6 || spacing != 1 {
+			t.Errorf("expected 5e6, 1 got %v, %v", minimum, spacing)
+		}
+	}
+}
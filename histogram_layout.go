@@ -0,0 +1,109 @@
+package hrtime
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// BinLayout defines how histogram bins are spaced.
+type BinLayout int
+
+const (
+	// LinearBins spaces bins evenly between minimum and the clamped maximum.
+	LinearBins BinLayout = iota
+	// LogBins spaces bins logarithmically between minimum and the clamped maximum,
+	// which shows both the body and the long tail of the distribution.
+	LogBins
+	// PercentileBins spaces bins such that each bin has an equal number of measurements.
+	PercentileBins
+)
+
+// String returns the name of the layout.
+func (layout BinLayout) String() string {
+	switch layout {
+	case LinearBins:
+		return "linear"
+	case LogBins:
+		return "log"
+	case PercentileBins:
+		return "percentile"
+	default:
+		return "BinLayout(" + strconv.Itoa(int(layout)) + ")"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (layout BinLayout) MarshalText() ([]byte, error) {
+	return []byte(layout.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (layout *BinLayout) UnmarshalText(text []byte) error {
+	for _, l := range []BinLayout{LinearBins, LogBins, PercentileBins} {
+		if l.String() == string(text) {
+			*layout = l
+			return nil
+		}
+	}
+	return errors.New("unknown bin layout " + strconv.Quote(string(text)))
+}
+
+// logBinStarts sets logarithmically spaced bin starts from min to max
+// and returns the end of the last bin.
+func logBinStarts(bins []HistogramBin, min, max float64, nice bool) (upper float64) {
+	if min < 1 {
+		min = 1
+	}
+	if max <= min {
+		max = min * 2
+	}
+
+	ratio := math.Pow(max/min, 1/float64(len(bins)))
+	for i := range bins {
+		start := min * math.Pow(ratio, float64(i))
+		if nice && i > 0 && round(start, 2) > bins[i-1].Start {
+			start = round(start, 2)
+		}
+		bins[i].Start = start
+	}
+	return min * math.Pow(ratio, float64(len(bins)))
+}
+
+// fillBins counts values of dist divided by divisor into bins with arbitrary starts.
+//
+// Values below the first bin are counted into the first bin and
+// values at or above upper are counted into the last bin.
+func fillBins(bins []HistogramBin, dist distribution, divisor, upper float64) {
+	dist.each(func(x float64, count int) {
+		x /= divisor
+		k := sort.Search(len(bins), func(i int) bool { return bins[i].Start > x }) - 1
+		if k < 0 {
+			k = 0
+		}
+		if x >= upper {
+			k = len(bins) - 1
+			bins[k].andAbove = true
+		}
+		bins[k].Count += count
+	})
+}
+
+// binLabelDigits returns the number of significant digits needed
+// to distinguish bin starts from each other.
+func binLabelDigits(bins []HistogramBin) int {
+	for digits := 3; digits < 8; digits++ {
+		distinct := true
+		for i := 1; i < len(bins); i++ {
+			if bins[i].Start != bins[i-1].Start && round(bins[i].Start, digits) == round(bins[i-1].Start, digits) {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			return digits
+		}
+	}
+	return 8
+}
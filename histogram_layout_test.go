@@ -0,0 +1,156 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleHistogramOptions_logBins() {
+	bench := hrtime.NewBenchmark(4096)
+	for bench.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+
+	fmt.Println(hrtime.NewHistogram(bench.Float64s(), &hrtime.HistogramOptions{
+		BinCount:  10,
+		NiceRange: true,
+		Layout:    hrtime.LogBins,
+	}))
+}
+
+func TestHistogramLogBins(t *testing.T) {
+	samples := []float64{}
+	for _, v := range []float64{10, 100, 1000, 10000} {
+		for i := 0; i < 10; i++ {
+			samples = append(samples, v)
+		}
+	}
+
+	hist := hrtime.NewHistogram(samples, &hrtime.HistogramOptions{
+		BinCount: 4,
+		Layout:   hrtime.LogBins,
+	})
+	for i, bin := range hist.Bins {
+		expected := 10 * math.Pow(10, float64(i)*3/4)
+		if math.Abs(bin.Start-expected) > 1e-6*expected {
+			t.Errorf("bin %d: expected start %v got %v", i, expected, bin.Start)
+		}
+	}
+
+	total := 0
+	for _, bin := range hist.Bins {
+		total += bin.Count
+	}
+	if total != len(samples) {
+		t.Errorf("expected %d measurements got %d", len(samples), total)
+	}
+	if hist.Bins[0].Count != 10 || hist.Bins[3].Count != 10 {
+		t.Errorf("unexpected bins %+v", hist.Bins)
+	}
+}
+
+func TestHistogramPercentileBins(t *testing.T) {
+	samples := make([]float64, 100)
+	for i := range samples {
+		samples[i] = float64(i * i)
+	}
+
+	hist := hrtime.NewHistogram(samples, &hrtime.HistogramOptions{
+		BinCount: 4,
+		Layout:   hrtime.PercentileBins,
+	})
+	for i, bin := range hist.Bins {
+		if bin.Count != 25 {
+			t.Errorf("bin %d: expected 25 measurements got %d", i, bin.Count)
+		}
+		if bin.Width != 1 {
+			t.Errorf("bin %d: expected equal width got %v", i, bin.Width)
+		}
+	}
+}
+
+func TestHistogramEdges(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{5, 15, 25, 150, 1500}, &hrtime.HistogramOptions{
+		Edges: []float64{10, 20, 100},
+	})
+	if len(hist.Bins) != 3 {
+		t.Fatalf("expected 3 bins got %d", len(hist.Bins))
+	}
+
+	// first bin starts at the minimum, because 5 is below the first edge
+	expected := []int{2, 1, 2}
+	for i, bin := range hist.Bins {
+		if bin.Start != []float64{5, 20, 100}[i] {
+			t.Errorf("bin %d: expected start %v", i, bin.Start)
+		}
+		if bin.Count != expected[i] {
+			t.Errorf("bin %d: expected %d got %d", i, expected[i], bin.Count)
+		}
+	}
+
+	lines := strings.Split(hist.String(), "\n")
+	last := ""
+	for _, line := range lines {
+		if strings.Contains(line, "[") {
+			last = line
+		}
+	}
+	if !strings.Contains(last, "100ns+") {
+		t.Errorf("expected last bin to be open ended:\n%v", hist)
+	}
+}
+
+func TestHistogramEdgesFirstBin(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{15, 25}, &hrtime.HistogramOptions{
+		Edges: []float64{10, 20},
+	})
+	if hist.Bins[0].Start != 10 {
+		t.Errorf("expected first bin to start at the edge got %v", hist.Bins[0].Start)
+	}
+
+	multi := hrtime.NewMultiHistogram(&hrtime.HistogramOptions{Edges: []float64{10, 20}},
+		hrtime.NewHistogram([]float64{5, 15}, &hrtime.HistogramOptions{BinCount: 2}))
+	if start := multi.Histograms[0].Bins[0].Start; start != 5 {
+		t.Errorf("expected first shared bin to start at the minimum got %v", start)
+	}
+}
+
+func TestHistogramEdgesUnsorted(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	hrtime.NewHistogram([]float64{1, 2, 3}, &hrtime.HistogramOptions{
+		Edges: []float64{10, 5},
+	})
+}
+
+func TestHistogramLabelsDistinct(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{1000, 1001, 1002, 1003}, &hrtime.HistogramOptions{
+		Edges: []float64{1000, 1001, 1002, 1003},
+	})
+
+	seen := map[string]bool{}
+	for _, line := range strings.Split(hist.String(), "\n") {
+		if !strings.Contains(line, "[") || strings.Contains(line, "avg") {
+			continue
+		}
+		label := strings.TrimSpace(line[:strings.Index(line, "[")])
+		if seen[label] {
+			t.Errorf("duplicate label %q:\n%v", label, hist)
+		}
+		seen[label] = true
+	}
+}
+
+func TestBinLayoutString(t *testing.T) {
+	if hrtime.LogBins.String() != "log" {
+		t.Errorf("got %q", hrtime.LogBins.String())
+	}
+}
@@ -0,0 +1,327 @@
+package hrtime
+
+import (
+	"io"
+	"math"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+// LogHistogram is a log-bucketed histogram with bounded memory.
+//
+// The bucket layout follows HdrHistogram: values are recorded with the
+// specified number of significant digits, such that the relative error of
+// each recorded value is at most 10^-significantDigits. Memory usage
+// depends only on the value range and precision, not on the number of
+// recorded values.
+//
+// LogHistogram must not be used concurrently.
+type LogHistogram struct {
+	lowest            int64
+	highest           int64
+	significantDigits int
+
+	unitMagnitude               uint
+	subBucketHalfCountMagnitude uint
+	subBucketCount              int64
+	subBucketHalfCount          int64
+	subBucketMask               int64
+
+	counts     []int64
+	totalCount int64
+	min, max   int64
+	sum        float64
+}
+
+// NewLogHistogram creates a new log-bucketed histogram for values
+// between min and max with the specified number of significant digits.
+//
+// Values outside of the range are clamped to it.
+// The significant digits must be between 1 and 5.
+func NewLogHistogram(min, max time.Duration, significantDigits int) *LogHistogram {
+	min = checkLogHistogram(min, max, significantDigits)
+
+	hist := &LogHistogram{
+		lowest:            int64(min),
+		highest:           int64(max),
+		significantDigits: significantDigits,
+	}
+
+	largestWithSingleUnitResolution := 2 * math.Pow10(significantDigits)
+	subBucketCountMagnitude := uint(math.Ceil(math.Log2(largestWithSingleUnitResolution)))
+	hist.subBucketHalfCountMagnitude = subBucketCountMagnitude - 1
+	hist.subBucketCount = int64(1) << subBucketCountMagnitude
+	hist.subBucketHalfCount = hist.subBucketCount / 2
+	hist.unitMagnitude = uint(bits.Len64(uint64(hist.lowest)) - 1)
+	hist.subBucketMask = (hist.subBucketCount - 1) << hist.unitMagnitude
+
+	bucketCount := 1
+	smallestUntrackable := hist.subBucketCount << hist.unitMagnitude
+	for smallestUntrackable <= hist.highest {
+		if smallestUntrackable > math.MaxInt64/2 {
+			bucketCount++
+			break
+		}
+		smallestUntrackable <<= 1
+		bucketCount++
+	}
+
+	hist.counts = make([]int64, int64(bucketCount+1)*hist.subBucketHalfCount)
+	hist.min, hist.max = math.MaxInt64, 0
+	return hist
+}
+
+// checkLogHistogram panics when the parameters of NewLogHistogram are
+// invalid and returns the adjusted min.
+func checkLogHistogram(min, max time.Duration, significantDigits int) time.Duration {
+	if significantDigits < 1 || significantDigits > 5 {
+		panic("significant digits must be between 1 and 5")
+	}
+	if min < 1 {
+		min = 1
+	}
+	if max < 2*min {
+		panic("max must be at least twice the min")
+	}
+	return min
+}
+
+// bucketIndices returns the bucket and sub-bucket of the value.
+func (hist *LogHistogram) bucketIndices(v int64) (bucket uint, subBucket int64) {
+	pow2Ceiling := uint(bits.Len64(uint64(v | hist.subBucketMask)))
+	bucket = pow2Ceiling - hist.unitMagnitude - (hist.subBucketHalfCountMagnitude + 1)
+	subBucket = v >> (bucket + hist.unitMagnitude)
+	return bucket, subBucket
+}
+
+// countsIndex returns the index of the value in counts.
+func (hist *LogHistogram) countsIndex(v int64) int {
+	bucket, subBucket := hist.bucketIndices(v)
+	base := int64(bucket+1) << hist.subBucketHalfCountMagnitude
+	return int(base + subBucket - hist.subBucketHalfCount)
+}
+
+// valueFromIndex returns the lowest value that maps to the index.
+func (hist *LogHistogram) valueFromIndex(index int) int64 {
+	bucket := (index >> hist.subBucketHalfCountMagnitude) - 1
+	subBucket := int64(index)&(hist.subBucketHalfCount-1) + hist.subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= hist.subBucketHalfCount
+		bucket = 0
+	}
+	return subBucket << (uint(bucket) + hist.unitMagnitude)
+}
+
+// equivalentRange returns the size of the range of values that
+// map to the same index as v.
+func (hist *LogHistogram) equivalentRange(v int64) int64 {
+	bucket, subBucket := hist.bucketIndices(v)
+	if subBucket >= hist.subBucketCount {
+		bucket++
+	}
+	return int64(1) << (hist.unitMagnitude + bucket)
+}
+
+// Record adds a measurement in nanoseconds.
+func (hist *LogHistogram) Record(ns float64) {
+	hist.RecordN(ns, 1)
+}
+
+// RecordN adds a measurement in nanoseconds n times.
+func (hist *LogHistogram) RecordN(ns float64, n int64) {
+	if n <= 0 {
+		return
+	}
+
+	v := int64(math.Round(ns))
+	if v < 0 {
+		v = 0
+	}
+	if v > hist.highest {
+		v = hist.highest
+	}
+
+	hist.counts[hist.countsIndex(v)] += n
+	hist.totalCount += n
+	hist.sum += float64(v) * float64(n)
+	if v < hist.min {
+		hist.min = v
+	}
+	if v > hist.max {
+		hist.max = v
+	}
+}
+
+// RecordDuration adds a measurement.
+func (hist *LogHistogram) RecordDuration(d time.Duration) {
+	hist.Record(float64(d))
+}
+
+// Merge adds all measurements from other to hist.
+func (hist *LogHistogram) Merge(other *LogHistogram) {
+	if other.totalCount == 0 {
+		return
+	}
+
+	if hist.lowest == other.lowest && hist.highest == other.highest && hist.significantDigits == other.significantDigits {
+		for i, count := range other.counts {
+			hist.counts[i] += count
+		}
+		hist.totalCount += other.totalCount
+		hist.sum += other.sum
+		if other.min < hist.min {
+			hist.min = other.min
+		}
+		if other.max > hist.max {
+			hist.max = other.max
+		}
+		return
+	}
+
+	// re-bucketing records the bucket midpoints, hence carry the exact
+	// sum, min and max, clamped to the range of hist like RecordN;
+	// the exact sum is unknown when values were clamped, in which case
+	// the sum of the clamped midpoints is kept
+	sum := hist.sum + other.sum
+	clamped := other.max > hist.highest
+	min, max := other.min, other.max
+	if min > hist.highest {
+		min = hist.highest
+	}
+	if max > hist.highest {
+		max = hist.highest
+	}
+	other.each(func(value float64, count int) {
+		hist.RecordN(value, int64(count))
+	})
+	if !clamped {
+		hist.sum = sum
+	}
+	if min < hist.min {
+		hist.min = min
+	}
+	if max > hist.max {
+		hist.max = max
+	}
+}
+
+// Reset removes all measurements.
+func (hist *LogHistogram) Reset() {
+	for i := range hist.counts {
+		hist.counts[i] = 0
+	}
+	hist.totalCount = 0
+	hist.sum = 0
+	hist.min, hist.max = math.MaxInt64, 0
+}
+
+// clone returns a copy of the histogram.
+func (hist *LogHistogram) clone() *LogHistogram {
+	clone := *hist
+	clone.counts = append(hist.counts[:0:0], hist.counts...)
+	return &clone
+}
+
+// Count returns the number of measurements.
+func (hist *LogHistogram) Count() int64 { return hist.totalCount }
+
+// Percentile returns the value at the specified percentile, e.g. 0.99.
+//
+// The result is accurate to the configured number of significant digits.
+func (hist *LogHistogram) Percentile(p float64) float64 {
+	if hist.totalCount == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return float64(hist.min)
+	}
+
+	target := int64(math.Ceil(p * float64(hist.totalCount)))
+	if target < 1 {
+		target = 1
+	}
+	if target > hist.totalCount {
+		target = hist.totalCount
+	}
+
+	cumulative := int64(0)
+	for i, count := range hist.counts {
+		cumulative += count
+		if cumulative >= target {
+			return hist.clamp(hist.highestEquivalent(hist.valueFromIndex(i)))
+		}
+	}
+	return float64(hist.max)
+}
+
+// highestEquivalent returns the largest value that maps to the same index as v.
+func (hist *LogHistogram) highestEquivalent(v int64) int64 {
+	return v + hist.equivalentRange(v) - 1
+}
+
+// clamp clamps the value to the recorded range.
+func (hist *LogHistogram) clamp(v int64) float64 {
+	if v < hist.min {
+		v = hist.min
+	}
+	if v > hist.max {
+		v = hist.max
+	}
+	return float64(v)
+}
+
+// RelativeError returns the maximum relative error of the recorded values.
+func (hist *LogHistogram) RelativeError() float64 {
+	return math.Pow10(-hist.significantDigits)
+}
+
+func (hist *LogHistogram) count() int       { return int(hist.totalCount) }
+func (hist *LogHistogram) minimum() float64 { return float64(hist.min) }
+func (hist *LogHistogram) maximum() float64 { return float64(hist.max) }
+func (hist *LogHistogram) mean() float64    { return hist.sum / float64(hist.totalCount) }
+
+func (hist *LogHistogram) quantile(p float64, _ Interpolation) float64 { return hist.Percentile(p) }
+
+func (hist *LogHistogram) each(fn func(value float64, count int)) {
+	for i, count := range hist.counts {
+		if count == 0 {
+			continue
+		}
+		lowest := hist.valueFromIndex(i)
+		fn(hist.clamp(lowest+hist.equivalentRange(lowest)/2), int(count))
+	}
+}
+
+// Histogram converts the log-bucketed histogram into a Histogram.
+func (hist *LogHistogram) Histogram(opts *HistogramOptions) *Histogram {
+	result := newHistogram(hist.clone(), opts)
+	result.RelativeError = hist.RelativeError()
+	return result
+}
+
+// WriteStatsTo writes formatted statistics to w.
+func (hist *LogHistogram) WriteStatsTo(w io.Writer) (int64, error) {
+	opts := defaultOptions
+	return hist.Histogram(&opts).WriteStatsTo(w)
+}
+
+// WriteTo writes formatted statistics and histogram to w.
+func (hist *LogHistogram) WriteTo(w io.Writer) (int64, error) {
+	opts := defaultOptions
+	return hist.Histogram(&opts).WriteTo(w)
+}
+
+// StringStats returns a string representation of the histogram stats.
+func (hist *LogHistogram) StringStats() string {
+	var buffer strings.Builder
+	_, _ = hist.WriteStatsTo(&buffer)
+	return buffer.String()
+}
+
+// String returns a string representation of the histogram.
+func (hist *LogHistogram) String() string {
+	var buffer strings.Builder
+	_, _ = hist.WriteTo(&buffer)
+	return buffer.String()
+}
@@ -0,0 +1,131 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleLogHistogram() {
+	hist := hrtime.NewLogHistogram(time.Nanosecond, time.Minute, 3)
+	for i := 0; i < 4096; i++ {
+		start := hrtime.Now()
+		time.Sleep(1000 * time.Nanosecond)
+		hist.RecordDuration(hrtime.Since(start))
+	}
+	fmt.Println(hist)
+}
+
+func TestLogHistogramPercentile(t *testing.T) {
+	for _, digits := range []int{1, 2, 3} {
+		hist := hrtime.NewLogHistogram(time.Nanosecond, time.Hour, digits)
+		const N = 100000
+		for i := 1; i <= N; i++ {
+			hist.Record(float64(i))
+		}
+		if hist.Count() != N {
+			t.Fatalf("expected %d got %d", N, hist.Count())
+		}
+
+		relerr := hist.RelativeError()
+		for _, p := range []float64{0.5, 0.9, 0.99, 0.999} {
+			exp := p * N
+			got := hist.Percentile(p)
+			if math.Abs(got-exp)/exp > relerr {
+				t.Errorf("digits %d: p%v got %v expected %v", digits, p*100, got, exp)
+			}
+		}
+		if hist.Percentile(0) != 1 || hist.Percentile(1) != N {
+			t.Errorf("digits %d: invalid range %v %v", digits, hist.Percentile(0), hist.Percentile(1))
+		}
+	}
+}
+
+func TestLogHistogramMerge(t *testing.T) {
+	all := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	a := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	b := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	c := hrtime.NewLogHistogram(time.Microsecond, time.Second, 2)
+	for i := 1; i <= 10000; i++ {
+		all.Record(float64(i * 10))
+		if i%2 == 0 {
+			a.Record(float64(i * 10))
+		} else {
+			b.Record(float64(i * 10))
+		}
+	}
+	a.Merge(b)
+	if a.String() != all.String() {
+		t.Errorf("merged histogram differs:\n%v\n%v", a, all)
+	}
+
+	c.Merge(all)
+	if c.Count() != all.Count() {
+		t.Errorf("invalid count after merge %v", c.Count())
+	}
+	if p := c.Percentile(0.5); math.Abs(p-50000)/50000 > c.RelativeError() {
+		t.Errorf("invalid p50 after merge %v", p)
+	}
+}
+
+func TestLogHistogramMergeMinMax(t *testing.T) {
+	a := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	b := hrtime.NewLogHistogram(time.Microsecond, time.Second, 1)
+	a.Record(123456)
+	a.Record(987654)
+
+	b.Merge(a)
+	hist := b.Histogram(&hrtime.HistogramOptions{BinCount: 1})
+	if hist.Minimum != 123456 || hist.Maximum != 987654 {
+		t.Errorf("expected exact min and max got %v %v", hist.Minimum, hist.Maximum)
+	}
+	if b.Percentile(0) != 123456 || b.Percentile(1) != 987654 {
+		t.Errorf("expected exact range got %v %v", b.Percentile(0), b.Percentile(1))
+	}
+}
+
+func TestLogHistogramMergeClamped(t *testing.T) {
+	a := hrtime.NewLogHistogram(time.Microsecond, time.Millisecond, 2)
+	b := hrtime.NewLogHistogram(time.Microsecond, time.Second, 2)
+	a.Record(float64(2 * time.Microsecond))
+	b.Record(float64(500 * time.Millisecond))
+
+	a.Merge(b)
+	hist := a.Histogram(&hrtime.HistogramOptions{BinCount: 1})
+	if hist.Maximum != float64(time.Millisecond) {
+		t.Errorf("expected maximum to be clamped got %v", hist.Maximum)
+	}
+	if hist.Average > hist.Maximum || hist.Average < hist.Minimum {
+		t.Errorf("average %v outside of [%v, %v]", hist.Average, hist.Minimum, hist.Maximum)
+	}
+}
+
+func TestLogHistogramMatchesHistogram(t *testing.T) {
+	hist := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	values := []float64{}
+	for i := 0; i < 1000; i++ {
+		v := float64(1000 + (i*7919)%5000)
+		values = append(values, v)
+		hist.Record(v)
+	}
+
+	opts := hrtime.HistogramOptions{BinCount: 10, NiceRange: true, ClampPercentile: 0.999}
+	exact := hrtime.NewHistogram(values, &opts)
+	approx := hist.Histogram(&opts)
+	if math.Abs(exact.Average-approx.Average) > 1e-9 {
+		t.Errorf("average differs %v %v", exact.Average, approx.Average)
+	}
+	if math.Abs(exact.P90-approx.P90)/exact.P90 > hist.RelativeError() {
+		t.Errorf("p90 differs %v %v", exact.P90, approx.P90)
+	}
+	total := 0
+	for _, bin := range approx.Bins {
+		total += bin.Count
+	}
+	if total != len(values) {
+		t.Errorf("bins contain %d values expected %d", total, len(values))
+	}
+}
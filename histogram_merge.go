@@ -0,0 +1,143 @@
+package hrtime
+
+import (
+	"math"
+	"sort"
+)
+
+// mergeAccuracy is the relative accuracy used when merging
+// histograms created from different kinds of measurements.
+const mergeAccuracy = 0.01
+
+// Merge combines histograms into a single histogram and recalculates
+// all statistics from the combined measurements.
+//
+// This allows aggregating results from shards, e.g. parallel test
+// processes or multiple machines. The result uses the options of
+// the first histogram.
+//
+// Histograms created from samples are merged exactly. Histograms created
+// from the same kind of Recorder are merged using the Recorder. Otherwise
+// the measurements are combined into a DDSketch and the result is approximate.
+//
+// All histograms must have been divided by the same amount.
+func Merge(hists ...*Histogram) *Histogram {
+	if len(hists) == 0 {
+		panic("must have at least 1 histogram")
+	}
+
+	divisor := hists[0].divisor
+	if divisor == 0 {
+		divisor = 1
+	}
+
+	dists := make([]distribution, 0, len(hists))
+	relativeError, rankError := 0.0, 0.0
+	for _, hist := range hists {
+		histDivisor := hist.divisor
+		if histDivisor == 0 {
+			histDivisor = 1
+		}
+		if histDivisor != divisor {
+			panic("histograms must be divided by the same amount")
+		}
+		if hist.Count > 0 && hist.dist == nil {
+			panic("histogram does not contain measurements")
+		}
+
+		relativeError = math.Max(relativeError, hist.RelativeError)
+		rankError = math.Max(rankError, hist.RankError)
+		if hist.dist != nil {
+			dists = append(dists, hist.dist)
+		}
+	}
+
+	opts := hists[0].opts
+	if opts.BinCount <= 0 && len(opts.Edges) == 0 {
+		opts = defaultOptions
+	}
+
+	dist, approximate := mergeDistributions(dists)
+	if approximate {
+		relativeError = math.Max(relativeError, mergeAccuracy)
+	}
+
+	result := newHistogram(dist, &opts)
+	result.RelativeError = relativeError
+	result.RankError = rankError
+	result.Width = hists[0].Width
+	if divisor != 1 {
+		result.Divide(int(divisor))
+	}
+	return result
+}
+
+// mergeDistributions combines dists into a new distribution.
+// It reports whether the result is an approximation of the measurements.
+func mergeDistributions(dists []distribution) (dist distribution, approximate bool) {
+	if len(dists) == 0 {
+		return sortedSamples(nil), false
+	}
+
+	switch first := dists[0].(type) {
+	case sortedSamples:
+		var all []float64
+		for _, dist := range dists {
+			samples, ok := dist.(sortedSamples)
+			if !ok {
+				return mergeSketch(dists), true
+			}
+			all = append(all, samples...)
+		}
+		sort.Float64s(all)
+		return sortedSamples(all), false
+	case *LogHistogram:
+		merged := first.clone()
+		for _, dist := range dists[1:] {
+			other, ok := dist.(*LogHistogram)
+			if !ok {
+				return mergeSketch(dists), true
+			}
+			merged.Merge(other)
+		}
+		return merged, false
+	case *TDigest:
+		merged := first.clone()
+		for _, dist := range dists[1:] {
+			other, ok := dist.(*TDigest)
+			if !ok {
+				return mergeSketch(dists), true
+			}
+			merged.Merge(other)
+		}
+		return merged, false
+	case *DDSketch:
+		merged := first.clone()
+		for _, dist := range dists[1:] {
+			other, ok := dist.(*DDSketch)
+			if !ok {
+				return mergeSketch(dists), true
+			}
+			merged.Merge(other)
+		}
+		return merged, false
+	default:
+		return mergeSketch(dists), true
+	}
+}
+
+// mergeSketch combines different kinds of distributions into a DDSketch.
+func mergeSketch(dists []distribution) *DDSketch {
+	sketch := NewDDSketch(mergeAccuracy)
+	for _, dist := range dists {
+		sum := sketch.sum + dist.mean()*float64(dist.count())
+		dist.each(func(value float64, count int) {
+			sketch.RecordN(value, int64(count))
+		})
+		// keep the exact sum and range, such that they are not approximated
+		sketch.sum = sum
+		sketch.min = math.Min(sketch.min, dist.minimum())
+		sketch.max = math.Max(sketch.max, dist.maximum())
+	}
+	return sketch
+}
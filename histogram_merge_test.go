@@ -0,0 +1,123 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleMerge() {
+	shards := []*hrtime.Histogram{}
+	for shard := 0; shard < 4; shard++ {
+		bench := hrtime.NewBenchmark(1024)
+		for bench.Next() {
+			time.Sleep(1000 * time.Nanosecond)
+		}
+		shards = append(shards, bench.Histogram(10))
+	}
+
+	fmt.Println(hrtime.Merge(shards...))
+}
+
+func TestMergeSamples(t *testing.T) {
+	opts := &hrtime.HistogramOptions{BinCount: 5, ShowDispersion: true}
+	a := hrtime.NewHistogram([]float64{1, 3, 5, 7, 9}, opts)
+	b := hrtime.NewHistogram([]float64{2, 4, 6, 8, 10}, opts)
+	all := hrtime.NewHistogram([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, opts)
+
+	merged := hrtime.Merge(a, b)
+	if merged.Count != 10 {
+		t.Errorf("expected 10 measurements got %d", merged.Count)
+	}
+	if merged.Minimum != all.Minimum || merged.Maximum != all.Maximum || merged.Average != all.Average {
+		t.Errorf("expected %v got %v", all.StringStats(), merged.StringStats())
+	}
+	if merged.P50 != all.P50 || merged.P90 != all.P90 || merged.StdDev != all.StdDev {
+		t.Errorf("expected %v got %v", all.StringStats(), merged.StringStats())
+	}
+	if len(merged.Bins) != 5 {
+		t.Errorf("expected options of the first histogram, got %d bins", len(merged.Bins))
+	}
+	if merged.RelativeError != 0 {
+		t.Errorf("expected exact result")
+	}
+}
+
+func TestMergeLogHistogram(t *testing.T) {
+	a := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	b := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	for i := 1; i <= 1000; i++ {
+		a.Record(float64(i))
+		b.Record(float64(i + 1000))
+	}
+
+	opts := &hrtime.HistogramOptions{BinCount: 10}
+	merged := hrtime.Merge(a.Histogram(opts), b.Histogram(opts))
+	if merged.Count != 2000 {
+		t.Errorf("expected 2000 measurements got %d", merged.Count)
+	}
+	if math.Abs(merged.P50-1000) > 1000*merged.RelativeError+1 {
+		t.Errorf("expected p50 near 1000 got %v", merged.P50)
+	}
+	if merged.RelativeError != a.RelativeError() {
+		t.Errorf("expected relative error %v got %v", a.RelativeError(), merged.RelativeError)
+	}
+}
+
+func TestMergeMixed(t *testing.T) {
+	digest := hrtime.NewTDigest(100)
+	samples := []float64{}
+	for i := 1; i <= 1000; i++ {
+		digest.Record(float64(i))
+		samples = append(samples, float64(i+1000))
+	}
+
+	opts := &hrtime.HistogramOptions{BinCount: 10}
+	merged := hrtime.Merge(digest.Histogram(opts), hrtime.NewHistogram(samples, opts))
+	if merged.Count != 2000 {
+		t.Errorf("expected 2000 measurements got %d", merged.Count)
+	}
+	if merged.Minimum != 1 || merged.Maximum != 2000 {
+		t.Errorf("expected range [1, 2000] got [%v, %v]", merged.Minimum, merged.Maximum)
+	}
+	if math.Abs(merged.Average-1000.5) > 1e-9 {
+		t.Errorf("expected exact average got %v", merged.Average)
+	}
+	if merged.RelativeError == 0 || merged.RankError != digest.RankError() {
+		t.Errorf("expected approximation errors, got %v and %v", merged.RelativeError, merged.RankError)
+	}
+	if math.Abs(merged.P50-1000)/1000 > 0.05 {
+		t.Errorf("expected p50 near 1000 got %v", merged.P50)
+	}
+}
+
+func TestMergeDivided(t *testing.T) {
+	opts := &hrtime.HistogramOptions{BinCount: 5}
+	a := hrtime.NewHistogram([]float64{10, 20}, opts)
+	b := hrtime.NewHistogram([]float64{30, 40}, opts)
+	a.Divide(10)
+	b.Divide(10)
+
+	merged := hrtime.Merge(a, b)
+	if merged.Minimum != 1 || merged.Maximum != 4 {
+		t.Errorf("expected range [1, 4] got [%v, %v]", merged.Minimum, merged.Maximum)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	hrtime.Merge(a, hrtime.NewHistogram([]float64{1}, opts))
+}
+
+func TestMergeEmpty(t *testing.T) {
+	opts := &hrtime.HistogramOptions{BinCount: 5}
+	merged := hrtime.Merge(hrtime.NewHistogram(nil, opts), hrtime.NewHistogram([]float64{5}, opts))
+	if merged.Count != 1 || merged.P50 != 5 {
+		t.Errorf("unexpected result %v", merged.StringStats())
+	}
+}
@@ -0,0 +1,295 @@
+package hrtime
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MultiLayout defines how MultiHistogram draws the histograms.
+type MultiLayout int
+
+const (
+	// SideBySide draws each histogram in a separate column.
+	SideBySide MultiLayout = iota
+	// Overlay draws histograms on top of each other using different glyphs.
+	Overlay
+)
+
+var (
+	multiGlyphs = []string{"█", "▓", "▒", "░"}
+	multiColors = []string{"\x1b[34m", "\x1b[31m", "\x1b[32m", "\x1b[33m", "\x1b[35m", "\x1b[36m"}
+)
+
+const colorReset = "\x1b[0m"
+
+// MultiHistogram contains several histograms binned into shared bins,
+// which makes it easier to compare them visually.
+type MultiHistogram struct {
+	// Labels are the names of the histograms.
+	Labels []string
+	// Histograms are copies of the histograms with shared bins.
+	//
+	// Bin widths are relative to the fraction of measurements in the bin,
+	// such that histograms with different number of measurements are comparable.
+	Histograms []*Histogram
+
+	// for pretty printing
+	Layout MultiLayout
+	Width  int
+	// Color uses ANSI terminal colors to distinguish the histograms.
+	Color bool
+}
+
+// NewMultiHistogram bins histograms into shared bins calculated from
+// the combined range of all histograms.
+//
+// Histograms must be created from measurements, e.g. with NewHistogram or
+// Recorder.Histogram, otherwise they are shown as empty.
+// PercentileBins layout is not meaningful for shared bins and uses linear bins instead.
+func NewMultiHistogram(opts *HistogramOptions, hists ...*Histogram) *MultiHistogram {
+	if opts.BinCount <= 0 && len(opts.Edges) == 0 {
+		panic("binCount must be larger than 0")
+	}
+
+	multi := &MultiHistogram{
+		Width: 40,
+	}
+
+	minimum, clampMaximum := math.Inf(1), math.Inf(-1)
+	for _, hist := range hists {
+		if hist.dist == nil {
+			continue
+		}
+		minimum = math.Min(minimum, hist.Minimum)
+
+		histMaximum := hist.Maximum
+		if opts.ClampPercentile > 0 {
+			histMaximum = hist.Percentile(opts.ClampPercentile)
+		}
+		clampMaximum = math.Max(clampMaximum, histMaximum)
+	}
+	if opts.ClampMaximum > 0 {
+		clampMaximum = opts.ClampMaximum
+	}
+
+	binCount := opts.BinCount
+	if len(opts.Edges) > 0 {
+		binCount = len(opts.Edges)
+	}
+
+	starts := make([]HistogramBin, binCount)
+	upper := math.Inf(1)
+	var start, spacing float64
+	empty := math.IsInf(minimum, 0)
+	if empty {
+		// all histograms are empty
+		minimum = 0
+	}
+	switch {
+	case len(opts.Edges) > 0:
+		for k, edge := range opts.Edges {
+			starts[k].Start = edge
+		}
+		// values below the first edge are counted into the first bin
+		if !empty {
+			starts[0].Start = math.Min(minimum, opts.Edges[0])
+		}
+	case opts.Layout == LogBins:
+		upper = logBinStarts(starts, minimum, clampMaximum, opts.NiceRange)
+	default:
+		if opts.NiceRange {
+			start, spacing = calculateNiceSteps(minimum, clampMaximum, binCount)
+		} else {
+			start, spacing = calculateSteps(minimum, clampMaximum, binCount)
+		}
+		for k := range starts {
+			starts[k].Start = spacing*float64(k) + start
+		}
+		starts[0].Start = minimum
+	}
+	linear := len(opts.Edges) == 0 && opts.Layout != LogBins
+
+	maxFraction := 0.0
+	for i, hist := range hists {
+		rebinned := *hist
+		rebinned.Bins = append([]HistogramBin{}, starts...)
+
+		if hist.dist != nil && (spacing > 0 || !linear) {
+			divisor := hist.divisor
+			if divisor == 0 {
+				divisor = 1
+			}
+			if linear {
+				fillLinearBins(rebinned.Bins, hist.dist, divisor, start, spacing)
+			} else {
+				fillBins(rebinned.Bins, hist.dist, divisor, upper)
+			}
+
+			total := float64(hist.dist.count())
+			for _, bin := range rebinned.Bins {
+				maxFraction = math.Max(maxFraction, float64(bin.Count)/total)
+			}
+		}
+
+		multi.Histograms = append(multi.Histograms, &rebinned)
+		multi.Labels = append(multi.Labels, "#"+strconv.Itoa(i+1))
+	}
+
+	for _, hist := range multi.Histograms {
+		if hist.dist == nil || maxFraction == 0 {
+			continue
+		}
+		total := float64(hist.dist.count())
+		for k := range hist.Bins {
+			bin := &hist.Bins[k]
+			bin.Width = float64(bin.Count) / total / maxFraction
+		}
+	}
+
+	return multi
+}
+
+// glyph returns the glyph used for the i-th histogram.
+func (multi *MultiHistogram) glyph(i int) string {
+	if multi.Layout == SideBySide || multi.Color {
+		return multiGlyphs[0]
+	}
+	return multiGlyphs[i%len(multiGlyphs)]
+}
+
+// paint returns s colored for the i-th histogram.
+func (multi *MultiHistogram) paint(i int, s string) string {
+	if !multi.Color || s == "" {
+		return s
+	}
+	return multiColors[i%len(multiColors)] + s + colorReset
+}
+
+// WriteTo writes the histograms to w.
+func (multi *MultiHistogram) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	write := func(s string) error {
+		n, err := io.WriteString(w, s)
+		written += int64(n)
+		return err
+	}
+
+	// legend
+	var legend strings.Builder
+	for i, label := range multi.Labels {
+		legend.WriteString("  ")
+		legend.WriteString(multi.paint(i, strings.Repeat(multi.glyph(i), 2)))
+		legend.WriteString(" ")
+		legend.WriteString(label)
+	}
+	legend.WriteString("\n")
+	if err := write(legend.String()); err != nil {
+		return written, err
+	}
+	if len(multi.Histograms) == 0 {
+		return written, nil
+	}
+
+	maxCountLength := 3
+	for _, hist := range multi.Histograms {
+		for _, bin := range hist.Bins {
+			x := int(math.Ceil(math.Log10(float64(bin.Count + 1))))
+			if x > maxCountLength {
+				maxCountLength = x
+			}
+		}
+	}
+
+	columnWidth := multi.Width
+	if multi.Layout == SideBySide {
+		columnWidth = multi.Width / len(multi.Histograms)
+	}
+
+	digits := binLabelDigits(multi.Histograms[0].Bins)
+	for k := range multi.Histograms[0].Bins {
+		andAbove := false
+		for _, hist := range multi.Histograms {
+			andAbove = andAbove || hist.Bins[k].andAbove
+		}
+
+		var line strings.Builder
+		marker := " "
+		if andAbove {
+			marker = "+"
+		}
+		fmt.Fprintf(&line, " %10v%s", time.Duration(round(multi.Histograms[0].Bins[k].Start, digits)), marker)
+
+		switch multi.Layout {
+		case Overlay:
+			counts := make([]string, len(multi.Histograms))
+			for i, hist := range multi.Histograms {
+				counts[i] = fmt.Sprintf("%*d", maxCountLength, hist.Bins[k].Count)
+			}
+			line.WriteString("[" + strings.Join(counts, "|") + "] ")
+			line.WriteString(multi.overlayBar(k, columnWidth))
+		default:
+			for i, hist := range multi.Histograms {
+				cells := int(float64(columnWidth) * hist.Bins[k].Width)
+				fmt.Fprintf(&line, "[%*d] ", maxCountLength, hist.Bins[k].Count)
+				line.WriteString(multi.paint(i, strings.Repeat(multi.glyph(i), cells)))
+				if i < len(multi.Histograms)-1 {
+					line.WriteString(strings.Repeat(" ", columnWidth-cells+1))
+				}
+			}
+		}
+
+		line.WriteString("\n")
+		if err := write(line.String()); err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// overlayBar draws bars of all histograms for bin k on top of each other,
+// such that shorter bars are drawn over the longer ones.
+func (multi *MultiHistogram) overlayBar(k int, width int) string {
+	order := make([]int, len(multi.Histograms))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return multi.Histograms[order[a]].Bins[k].Width > multi.Histograms[order[b]].Bins[k].Width
+	})
+
+	owner := make([]int, width)
+	for i := range owner {
+		owner[i] = -1
+	}
+	for _, i := range order {
+		cells := int(float64(width) * multi.Histograms[i].Bins[k].Width)
+		for c := 0; c < cells && c < width; c++ {
+			owner[c] = i
+		}
+	}
+
+	var bar strings.Builder
+	for start := 0; start < width && owner[start] >= 0; {
+		end := start
+		for end < width && owner[end] == owner[start] {
+			end++
+		}
+		i := owner[start]
+		bar.WriteString(multi.paint(i, strings.Repeat(multi.glyph(i), end-start)))
+		start = end
+	}
+	return bar.String()
+}
+
+// String returns a string representation of the histograms.
+func (multi *MultiHistogram) String() string {
+	var buffer strings.Builder
+	_, _ = multi.WriteTo(&buffer)
+	return buffer.String()
+}
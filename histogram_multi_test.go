@@ -0,0 +1,96 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleMultiHistogram() {
+	const numberOfExperiments = 4096
+	before := hrtime.NewBenchmark(numberOfExperiments)
+	for before.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+	after := hrtime.NewBenchmark(numberOfExperiments)
+	for after.Next() {
+		time.Sleep(2000 * time.Nanosecond)
+	}
+
+	multi := hrtime.NewMultiHistogram(&hrtime.HistogramOptions{
+		BinCount:        10,
+		NiceRange:       true,
+		ClampPercentile: 0.999,
+	}, before.Histogram(10), after.Histogram(10))
+	multi.Labels = []string{"before", "after"}
+	multi.Layout = hrtime.Overlay
+	fmt.Println(multi)
+}
+
+func TestMultiHistogram(t *testing.T) {
+	a := hrtime.NewHistogram([]float64{100, 110, 120, 130}, &hrtime.HistogramOptions{BinCount: 4})
+	b := hrtime.NewHistogram([]float64{300, 310, 320, 330, 340, 350, 360, 370}, &hrtime.HistogramOptions{BinCount: 4})
+
+	multi := hrtime.NewMultiHistogram(&hrtime.HistogramOptions{BinCount: 5, NiceRange: true}, a, b)
+	if len(multi.Histograms) != 2 {
+		t.Fatalf("expected 2 histograms")
+	}
+
+	for k := range multi.Histograms[0].Bins {
+		if multi.Histograms[0].Bins[k].Start != multi.Histograms[1].Bins[k].Start {
+			t.Errorf("bin %d not shared", k)
+		}
+	}
+	for i, hist := range multi.Histograms {
+		total := 0
+		for _, bin := range hist.Bins {
+			total += bin.Count
+		}
+		if exp := []int{4, 8}[i]; total != exp {
+			t.Errorf("histogram %d: expected %d values got %d", i, exp, total)
+		}
+	}
+	if a.Bins[0].Count+a.Bins[1].Count+a.Bins[2].Count+a.Bins[3].Count != 4 {
+		t.Errorf("original histogram modified")
+	}
+
+	multi.Labels = []string{"before", "after"}
+	for _, layout := range []hrtime.MultiLayout{hrtime.SideBySide, hrtime.Overlay} {
+		multi.Layout = layout
+		out := multi.String()
+		if !strings.Contains(out, "before") || !strings.Contains(out, "after") {
+			t.Errorf("missing legend:\n%s", out)
+		}
+		if strings.Count(out, "\n") != 6 {
+			t.Errorf("expected 6 lines:\n%s", out)
+		}
+		t.Log("\n" + out)
+	}
+
+	multi.Color = true
+	if out := multi.String(); !strings.Contains(out, "\x1b[") {
+		t.Errorf("missing colors:\n%q", out)
+	}
+}
+
+func TestMultiHistogramLogBins(t *testing.T) {
+	a := hrtime.NewHistogram([]float64{10, 20, 30}, &hrtime.HistogramOptions{BinCount: 4})
+	b := hrtime.NewHistogram([]float64{1000, 2000, 3000}, &hrtime.HistogramOptions{BinCount: 4})
+
+	multi := hrtime.NewMultiHistogram(&hrtime.HistogramOptions{BinCount: 4, Layout: hrtime.LogBins}, a, b)
+	for i, hist := range multi.Histograms {
+		total := 0
+		for _, bin := range hist.Bins {
+			total += bin.Count
+		}
+		if total != 3 {
+			t.Errorf("histogram %d: expected 3 measurements got %d", i, total)
+		}
+	}
+	if multi.Histograms[0].Bins[0].Count != 3 || multi.Histograms[1].Bins[3].Count != 3 {
+		t.Errorf("unexpected bins:\n%v", multi)
+	}
+}
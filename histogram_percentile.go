@@ -0,0 +1,119 @@
+package hrtime
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// Interpolation defines how percentiles are calculated from samples.
+type Interpolation int
+
+const (
+	// NearestRank uses the sample closest to the percentile rank.
+	NearestRank Interpolation = iota
+	// Linear interpolates linearly between the two closest samples.
+	Linear
+)
+
+// String returns the name of the interpolation method.
+func (method Interpolation) String() string {
+	switch method {
+	case NearestRank:
+		return "nearest-rank"
+	case Linear:
+		return "linear"
+	default:
+		return "Interpolation(" + strconv.Itoa(int(method)) + ")"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (method Interpolation) MarshalText() ([]byte, error) {
+	return []byte(method.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (method *Interpolation) UnmarshalText(text []byte) error {
+	for _, m := range []Interpolation{NearestRank, Linear} {
+		if m.String() == string(text) {
+			*method = m
+			return nil
+		}
+	}
+	return errors.New("unknown interpolation " + strconv.Quote(string(text)))
+}
+
+// Quantile is the value at percentile P.
+type Quantile struct {
+	P     float64 `json:"p"`
+	Value float64 `json:"value"`
+}
+
+// Label returns the short name of the percentile, e.g. "p99.5".
+func (q Quantile) Label() string {
+	return "p" + strconv.FormatFloat(round(q.P*100, 6), 'f', -1, 64)
+}
+
+// Percentile returns the value at the specified percentile, e.g. 0.95 or 0.995.
+//
+// Percentiles are calculated from the measurements the histogram was created
+// from using the interpolation method in HistogramOptions. Histograms created
+// from a Recorder use the approximation of the recorder instead.
+func (hist *Histogram) Percentile(p float64) float64 {
+	if hist.dist == nil {
+		return 0
+	}
+	divisor := hist.divisor
+	if divisor == 0 {
+		divisor = 1
+	}
+	return hist.dist.quantile(p, hist.interpolation) / divisor
+}
+
+// Percentiles returns the values at the specified percentiles.
+func (hist *Histogram) Percentiles(ps ...float64) []float64 {
+	values := make([]float64, len(ps))
+	for i, p := range ps {
+		values[i] = hist.Percentile(p)
+	}
+	return values
+}
+
+// Cumulative returns the number of measurements less than or equal to
+// each of the bounds, which must be in ascending order.
+//
+// Histograms without measurements, e.g. read from JSON, approximate
+// the counts using the bins.
+func (hist *Histogram) Cumulative(bounds []float64) []int {
+	counts := make([]int, len(bounds))
+	if hist.dist == nil {
+		for i, bin := range hist.Bins {
+			// all measurements in the bin are below the start of the next bin
+			end := hist.Maximum
+			if i+1 < len(hist.Bins) {
+				end = hist.Bins[i+1].Start
+			}
+			k := sort.SearchFloat64s(bounds, end)
+			if k < len(bounds) {
+				counts[k] += bin.Count
+			}
+		}
+	} else {
+		divisor := hist.divisor
+		if divisor == 0 {
+			divisor = 1
+		}
+		hist.dist.each(func(value float64, count int) {
+			k := sort.SearchFloat64s(bounds, value/divisor)
+			if k < len(bounds) {
+				counts[k] += count
+			}
+		})
+	}
+
+	for k := 1; k < len(counts); k++ {
+		counts[k] += counts[k-1]
+	}
+	return counts
+}
@@ -0,0 +1,91 @@
+package hrtime_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	values := make([]float64, 100)
+	for i := range values {
+		values[len(values)-i-1] = float64((i + 1) * 10)
+	}
+
+	nearest := hrtime.NewHistogram(values, &hrtime.HistogramOptions{BinCount: 10})
+	linear := hrtime.NewHistogram(values, &hrtime.HistogramOptions{BinCount: 10, Interpolation: hrtime.Linear})
+
+	tests := []struct {
+		p       float64
+		nearest float64
+		linear  float64
+	}{
+		{0, 10, 10},
+		{0.5, 510, 505},
+		{0.95, 960, 950.5},
+		{0.995, 1000, 995.05},
+		{1, 1000, 1000},
+	}
+	for _, test := range tests {
+		if got := nearest.Percentile(test.p); got != test.nearest {
+			t.Errorf("nearest p%v: got %v expected %v", test.p*100, got, test.nearest)
+		}
+		if got := linear.Percentile(test.p); got-test.linear > 1e-9 || test.linear-got > 1e-9 {
+			t.Errorf("linear p%v: got %v expected %v", test.p*100, got, test.linear)
+		}
+	}
+
+	if got := nearest.Percentiles(0.5, 1); !reflect.DeepEqual(got, []float64{510, 1000}) {
+		t.Errorf("invalid percentiles %v", got)
+	}
+
+	nearest.Divide(10)
+	if got := nearest.Percentile(0.5); got != 51 {
+		t.Errorf("divided p50: got %v expected 51", got)
+	}
+
+	var empty hrtime.Histogram
+	if got := empty.Percentile(0.5); got != 0 {
+		t.Errorf("empty histogram: got %v", got)
+	}
+}
+
+func TestHistogramOptionsPercentiles(t *testing.T) {
+	durations := []time.Duration{}
+	for i := 1; i <= 1000; i++ {
+		durations = append(durations, time.Duration(i)*time.Microsecond)
+	}
+	hist := hrtime.NewDurationHistogram(durations, &hrtime.HistogramOptions{
+		BinCount:    10,
+		Percentiles: []float64{0.95, 0.995},
+	})
+
+	exp := []hrtime.Quantile{{P: 0.95, Value: 951000}, {P: 0.995, Value: 996000}}
+	if !reflect.DeepEqual(hist.Quantiles, exp) {
+		t.Errorf("got %v expected %v", hist.Quantiles, exp)
+	}
+
+	stats := hist.StringStats()
+	if !strings.Contains(stats, "p95 951µs;  p99.5 996µs;") {
+		t.Errorf("percentiles missing from stats:\n%s", stats)
+	}
+}
+
+func TestHistogramCumulative(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{1, 2, 2, 3, 5, 8}, &hrtime.HistogramOptions{BinCount: 4})
+	counts := hist.Cumulative([]float64{0, 2, 4, 8, 100})
+	expected := []int{0, 3, 4, 6, 6}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %v got %v", expected, counts)
+	}
+
+	hist.Divide(2)
+	counts = hist.Cumulative([]float64{1, 4})
+	expected = []int{3, 6}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("divided: expected %v got %v", expected, counts)
+	}
+}
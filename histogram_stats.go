@@ -0,0 +1,151 @@
+package hrtime
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// calculateDispersion calculates dispersion statistics from hist.dist.
+func (hist *Histogram) calculateDispersion() {
+	dist := hist.dist
+	n := float64(dist.count())
+	mean := dist.mean()
+
+	var m2, m3, m4 float64
+	dist.each(func(value float64, count int) {
+		d := value - mean
+		d2 := d * d
+		m2 += d2 * float64(count)
+		m3 += d2 * d * float64(count)
+		m4 += d2 * d2 * float64(count)
+	})
+	m2, m3, m4 = m2/n, m3/n, m4/n
+
+	if n > 1 {
+		hist.StdDev = math.Sqrt(m2 * n / (n - 1))
+	}
+	if m2 > 0 && n > 2 {
+		// adjust the population skewness g1 for the sample size
+		g1 := m3 / math.Pow(m2, 1.5)
+		hist.Skewness = g1 * math.Sqrt(n*(n-1)) / (n - 2)
+	}
+	if m2 > 0 && n > 3 {
+		// adjust the population excess kurtosis g2 for the sample size
+		g2 := m4/(m2*m2) - 3
+		hist.Kurtosis = ((n+1)*g2 + 6) * (n - 1) / ((n - 2) * (n - 3))
+	}
+
+	hist.IQR = hist.Percentile(0.75) - hist.Percentile(0.25)
+
+	median := hist.Percentile(0.5)
+	deviations := []weightedValue{}
+	dist.each(func(value float64, count int) {
+		deviations = append(deviations, weightedValue{math.Abs(value - median), count})
+	})
+	sort.Slice(deviations, func(i, k int) bool { return deviations[i].value < deviations[k].value })
+
+	half := (dist.count() + 1) / 2
+	cumulative := 0
+	for _, deviation := range deviations {
+		cumulative += deviation.count
+		if cumulative >= half {
+			hist.MAD = deviation.value
+			break
+		}
+	}
+}
+
+// weightedValue is a value that occurs count times.
+type weightedValue struct {
+	value float64
+	count int
+}
+
+// CV returns the coefficient of variation, the ratio of standard deviation to average.
+func (hist *Histogram) CV() float64 {
+	if hist.Average == 0 {
+		return 0
+	}
+	return hist.StdDev / hist.Average
+}
+
+// ConfidenceInterval is a range that contains the true value of a statistic
+// with the specified confidence.
+type ConfidenceInterval struct {
+	Lower      float64
+	Upper      float64
+	Confidence float64
+}
+
+// Contains returns whether the interval contains v.
+func (ci ConfidenceInterval) Contains(v float64) bool {
+	return ci.Lower <= v && v <= ci.Upper
+}
+
+// BootstrapMedian estimates the confidence interval for the median,
+// e.g. with confidence 0.95, using the specified number of resamples.
+//
+// Each resample draws as many values as there are measurements.
+func (hist *Histogram) BootstrapMedian(resamples int, confidence float64) ConfidenceInterval {
+	return hist.bootstrap(resamples, confidence, func(sorted []float64) float64 {
+		return sortedSamples(sorted).quantile(0.5, hist.interpolation)
+	})
+}
+
+// BootstrapMean estimates the confidence interval for the average,
+// e.g. with confidence 0.95, using the specified number of resamples.
+//
+// Each resample draws as many values as there are measurements.
+func (hist *Histogram) BootstrapMean(resamples int, confidence float64) ConfidenceInterval {
+	return hist.bootstrap(resamples, confidence, func(sorted []float64) float64 {
+		return sortedSamples(sorted).mean()
+	})
+}
+
+// bootstrap estimates the confidence interval of statistic using resampling.
+func (hist *Histogram) bootstrap(resamples int, confidence float64, statistic func(sorted []float64) float64) ConfidenceInterval {
+	ci := ConfidenceInterval{Confidence: confidence}
+	if hist.dist == nil || resamples <= 0 {
+		return ci
+	}
+
+	divisor := hist.divisor
+	if divisor == 0 {
+		divisor = 1
+	}
+	values, cumulative := cumulativeCounts(hist.dist)
+	for i := range values {
+		values[i] /= divisor
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	estimates := make([]float64, resamples)
+	sample := make([]float64, hist.dist.count())
+	total := cumulative[len(cumulative)-1]
+	for i := range estimates {
+		for k := range sample {
+			rank := rng.Intn(total)
+			sample[k] = values[sort.SearchInts(cumulative, rank+1)]
+		}
+		sort.Float64s(sample)
+		estimates[i] = statistic(sample)
+	}
+	sort.Float64s(estimates)
+
+	alpha := (1 - confidence) / 2
+	ci.Lower = sortedSamples(estimates).quantile(alpha, Linear)
+	ci.Upper = sortedSamples(estimates).quantile(1-alpha, Linear)
+	return ci
+}
+
+// cumulativeCounts returns distinct values of dist and their cumulative counts.
+func cumulativeCounts(dist distribution) (values []float64, cumulative []int) {
+	total := 0
+	dist.each(func(value float64, count int) {
+		total += count
+		values = append(values, value)
+		cumulative = append(cumulative, total)
+	})
+	return values, cumulative
+}
@@ -0,0 +1,80 @@
+package hrtime_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/loov/hrtime"
+)
+
+func TestHistogramDispersion(t *testing.T) {
+	values := []float64{9, 1, 8, 2, 7, 3, 6, 4, 5}
+	hist := hrtime.NewHistogram(values, &hrtime.HistogramOptions{
+		BinCount:       3,
+		Interpolation:  hrtime.Linear,
+		ShowDispersion: true,
+	})
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-3 }
+	if !near(hist.StdDev, math.Sqrt(7.5)) {
+		t.Errorf("stddev: got %v", hist.StdDev)
+	}
+	if !near(hist.CV(), math.Sqrt(7.5)/5) {
+		t.Errorf("cv: got %v", hist.CV())
+	}
+	if hist.MAD != 2 {
+		t.Errorf("mad: got %v", hist.MAD)
+	}
+	if hist.IQR != 4 {
+		t.Errorf("iqr: got %v", hist.IQR)
+	}
+	if !near(hist.Skewness, 0) {
+		t.Errorf("skewness: got %v", hist.Skewness)
+	}
+	g2 := 708.0/9/(60.0/9*60.0/9) - 3
+	if !near(hist.Kurtosis, (10*g2+6)*8/(7*6)) {
+		t.Errorf("kurtosis: got %v", hist.Kurtosis)
+	}
+
+	if stats := hist.StringStats(); !strings.Contains(stats, "stddev") || !strings.Contains(stats, "kurtosis") {
+		t.Errorf("dispersion missing from stats:\n%s", stats)
+	}
+}
+
+func TestHistogramSkewness(t *testing.T) {
+	values := []float64{1, 2, 3, 10}
+	hist := hrtime.NewHistogram(values, &hrtime.HistogramOptions{BinCount: 3, ShowDispersion: true})
+
+	// population moments around the mean 4
+	m2 := (9 + 4 + 1 + 36) / 4.0
+	m3 := (-27 - 8 - 1 + 216) / 4.0
+	g1 := m3 / math.Pow(m2, 1.5)
+	if expected := g1 * math.Sqrt(4*3) / 2; math.Abs(hist.Skewness-expected) > 1e-9 {
+		t.Errorf("skewness: expected %v got %v", expected, hist.Skewness)
+	}
+}
+
+func TestHistogramDispersionDisabled(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{1, 2, 3, 10}, &hrtime.HistogramOptions{BinCount: 3})
+	if hist.StdDev != 0 || hist.MAD != 0 || hist.IQR != 0 {
+		t.Errorf("expected no dispersion statistics got %v %v %v", hist.StdDev, hist.MAD, hist.IQR)
+	}
+	if strings.Contains(hist.StringStats(), "stddev") {
+		t.Errorf("unexpected dispersion in stats:\n%s", hist.StringStats())
+	}
+}
+
+func TestHistogramBootstrap(t *testing.T) {
+	values, _ := lognormal(1000)
+	hist := hrtime.NewHistogram(values, &hrtime.HistogramOptions{BinCount: 10})
+
+	median := hist.BootstrapMedian(200, 0.95)
+	if !median.Contains(hist.P50) || median.Lower >= median.Upper {
+		t.Errorf("median %v not in %+v", hist.P50, median)
+	}
+	mean := hist.BootstrapMean(200, 0.95)
+	if !mean.Contains(hist.Average) || mean.Lower >= mean.Upper {
+		t.Errorf("mean %v not in %+v", hist.Average, mean)
+	}
+}
@@ -0,0 +1,118 @@
+package hrbench
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/loov/hrtime"
+)
+
+// SamplesPerRun is the number of samples reconstructed from a run with percentiles.
+const SamplesPerRun = 100
+
+// Units returns the timing units of the benchmark, e.g. "ns" or "tsc",
+// which have percentile metrics or an average "ns/op" or "tsc/op".
+func (bench *Benchmark) Units() []string {
+	seen := map[string]bool{}
+	var units []string
+	for _, run := range bench.Runs {
+		for metric := range run.Metrics {
+			unit, statistic := splitMetric(metric)
+			isTiming := statistic == "op" && (unit == "ns" || unit == "tsc")
+			if !isTiming && !isPercentile(statistic) {
+				continue
+			}
+			if !seen[unit] {
+				seen[unit] = true
+				units = append(units, unit)
+			}
+		}
+	}
+	sort.Strings(units)
+	return units
+}
+
+// Quantiles returns the percentiles of unit in the run sorted by percentile,
+// e.g. "ns/p50" metric as hrtime.Quantile{P: 0.5}.
+func (run *Run) Quantiles(unit string) []hrtime.Quantile {
+	var quantiles []hrtime.Quantile
+	for metric, value := range run.Metrics {
+		metricUnit, statistic := splitMetric(metric)
+		if metricUnit != unit || !isPercentile(statistic) {
+			continue
+		}
+		p, _ := strconv.ParseFloat(statistic[1:], 64)
+		quantiles = append(quantiles, hrtime.Quantile{P: p / 100, Value: value})
+	}
+	sort.Slice(quantiles, func(i, k int) bool {
+		return quantiles[i].P < quantiles[k].P
+	})
+	return quantiles
+}
+
+// Samples reconstructs approximate measurements in unit, e.g. "ns" or "tsc".
+//
+// When a run reports percentiles, it contributes SamplesPerRun samples
+// interpolated linearly between the percentiles. Values below the lowest and
+// above the highest percentile are unknown, hence they are clamped to them.
+// Otherwise the run contributes its "/op" average.
+func (bench *Benchmark) Samples(unit string) []float64 {
+	var samples []float64
+	for _, run := range bench.Runs {
+		quantiles := run.Quantiles(unit)
+		if len(quantiles) == 0 {
+			if average, ok := run.Metrics[unit+"/op"]; ok {
+				samples = append(samples, average)
+			}
+			continue
+		}
+
+		for i := 0; i < SamplesPerRun; i++ {
+			p := (float64(i) + 0.5) / SamplesPerRun
+			samples = append(samples, interpolate(quantiles, p))
+		}
+	}
+	return samples
+}
+
+// Float64s returns the reconstructed measurements in nanoseconds.
+func (bench *Benchmark) Float64s() []float64 { return bench.Samples("ns") }
+
+// Histogram creates a histogram from the reconstructed measurements in unit.
+func (bench *Benchmark) Histogram(unit string, opts *hrtime.HistogramOptions) *hrtime.Histogram {
+	return hrtime.NewHistogram(bench.Samples(unit), opts)
+}
+
+// interpolate returns the value at percentile p using sorted quantiles.
+func interpolate(quantiles []hrtime.Quantile, p float64) float64 {
+	first, last := quantiles[0], quantiles[len(quantiles)-1]
+	if p <= first.P {
+		return first.Value
+	}
+	if p >= last.P {
+		return last.Value
+	}
+
+	k := sort.Search(len(quantiles), func(i int) bool { return quantiles[i].P >= p })
+	low, high := quantiles[k-1], quantiles[k]
+	return low.Value + (high.Value-low.Value)*(p-low.P)/(high.P-low.P)
+}
+
+// splitMetric splits "ns/p50" into "ns" and "p50".
+func splitMetric(metric string) (unit, statistic string) {
+	slash := strings.LastIndexByte(metric, '/')
+	if slash < 0 {
+		return metric, ""
+	}
+	return metric[:slash], metric[slash+1:]
+}
+
+// isPercentile checks whether statistic is a percentile, e.g. "p99.9".
+func isPercentile(statistic string) bool {
+	if len(statistic) < 2 || statistic[0] != 'p' {
+		return false
+	}
+	p, err := strconv.ParseFloat(statistic[1:], 64)
+	return err == nil && p >= 0 && p <= 100
+}
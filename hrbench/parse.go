@@ -0,0 +1,193 @@
+// Package hrbench parses Go benchmark text format, e.g. output of `go test -bench`.
+//
+// It understands the percentile metrics reported by hrtesting, such as
+// "ns/p50" and "tsc/p99", and combines `-count` repetitions of the same
+// benchmark into a single Benchmark:
+//
+//     benchmarks, err := hrbench.Parse(file)
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//     for _, bench := range benchmarks {
+//         fmt.Println(bench.Name)
+//         fmt.Println(bench.Histogram("ns", &hrtime.HistogramOptions{BinCount: 10}))
+//     }
+package hrbench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Benchmark contains all repetitions of a single benchmark.
+type Benchmark struct {
+	// Name is the name of the benchmark without the GOMAXPROCS suffix.
+	Name string
+	// Procs is the GOMAXPROCS suffix of the benchmark name, or 1 when missing.
+	Procs int
+	// Config contains the configuration lines in effect, e.g. "pkg" or "goos".
+	Config map[string]string
+	// Runs are the repetitions of the benchmark.
+	Runs []Run
+}
+
+// Run is a single result line of a benchmark.
+type Run struct {
+	// Iterations is the number of iterations in the run.
+	Iterations int
+	// Metrics contains values by unit, e.g. "ns/op" or "ns/p99".
+	Metrics map[string]float64
+}
+
+// Parse reads benchmark results from r.
+//
+// Results of the same benchmark with the same configuration
+// are combined, in the order they first appear. Lines that are
+// not results or configuration, such as the output of `go test -v`,
+// are skipped.
+func Parse(r io.Reader) ([]*Benchmark, error) {
+	var benchmarks []*Benchmark
+	byKey := map[string]*Benchmark{}
+	config := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		// indented lines are output of b.Log, not configuration
+		if key, value, ok := parseConfig(line); ok {
+			config = copyConfig(config)
+			config[key] = value
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+		if !isBenchmarkLine(line) {
+			continue
+		}
+		// `go test -v` prints the name of the benchmark before the results
+		if len(strings.Fields(line)) < 2 {
+			continue
+		}
+
+		name, procs, run, err := parseRun(line)
+		if err != nil {
+			return benchmarks, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+
+		key := configKey(config) + "\x00" + name + "-" + strconv.Itoa(procs)
+		bench, ok := byKey[key]
+		if !ok {
+			bench = &Benchmark{
+				Name:   name,
+				Procs:  procs,
+				Config: config,
+			}
+			byKey[key] = bench
+			benchmarks = append(benchmarks, bench)
+		}
+		bench.Runs = append(bench.Runs, run)
+	}
+
+	return benchmarks, scanner.Err()
+}
+
+// parseConfig parses unindented "key: value" configuration line.
+func parseConfig(line string) (key, value string, ok bool) {
+	colon := strings.IndexByte(line, ':')
+	if colon <= 0 {
+		return "", "", false
+	}
+	key = line[:colon]
+	for i, r := range key {
+		if i == 0 && !unicode.IsLower(r) {
+			return "", "", false
+		}
+		if unicode.IsSpace(r) || unicode.IsUpper(r) {
+			return "", "", false
+		}
+	}
+	return key, strings.TrimSpace(line[colon+1:]), true
+}
+
+// isBenchmarkLine checks whether line starts with a benchmark name.
+func isBenchmarkLine(line string) bool {
+	if !strings.HasPrefix(line, "Benchmark") {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(line[len("Benchmark"):])
+	return next == utf8.RuneError || !unicode.IsLower(next)
+}
+
+// parseRun parses a benchmark result line.
+func parseRun(line string) (name string, procs int, run Run, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields)%2 != 0 {
+		return "", 0, run, fmt.Errorf("invalid benchmark line %q", line)
+	}
+
+	name, procs = splitProcs(fields[0])
+	run.Iterations, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, run, fmt.Errorf("invalid iterations %q", fields[1])
+	}
+
+	run.Metrics = make(map[string]float64, (len(fields)-2)/2)
+	for i := 2; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return "", 0, run, fmt.Errorf("invalid value %q", fields[i])
+		}
+		run.Metrics[fields[i+1]] = value
+	}
+	return name, procs, run, nil
+}
+
+// splitProcs splits "BenchmarkName-8" into "BenchmarkName" and 8.
+func splitProcs(name string) (string, int) {
+	dash := strings.LastIndexByte(name, '-')
+	if dash < 0 {
+		return name, 1
+	}
+	procs, err := strconv.Atoi(name[dash+1:])
+	if err != nil || procs <= 0 {
+		return name, 1
+	}
+	return name[:dash], procs
+}
+
+// copyConfig copies config, such that earlier benchmarks keep their configuration.
+func copyConfig(config map[string]string) map[string]string {
+	copied := make(map[string]string, len(config)+1)
+	for key, value := range config {
+		copied[key] = value
+	}
+	return copied
+}
+
+// configKey returns a string that uniquely identifies config.
+func configKey(config map[string]string) string {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteString(": ")
+		b.WriteString(config[key])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,187 @@
+package hrbench_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/hrbench"
+)
+
+const output = `goos: linux
+goarch: amd64
+pkg: github.com/loov/hrtime/hrtesting
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkReport-8      	 1000000	      1000 ns/op	       900.0 ns/p50	      1100 ns/p90	      2000 ns/p99
+BenchmarkReport-8      	 1000000	      1010 ns/op	       910.0 ns/p50	      1110 ns/p90	      2100 ns/p99
+BenchmarkTSCReport-8   	 1000000	      3000 tsc/p50	      3300 tsc/p90	      6000 tsc/p99
+BenchmarkReport-8      	 1000000	      1020 ns/op	       920.0 ns/p50	      1120 ns/p90	      2200 ns/p99
+BenchmarkPlain         	      50	  20000000 ns/op	     64 B/op	       2 allocs/op
+PASS
+ok  	github.com/loov/hrtime/hrtesting	5.123s
+pkg: github.com/loov/hrtime/other
+BenchmarkPlain         	      60	  21000000 ns/op
+`
+
+func Example() {
+	benchmarks, err := hrbench.Parse(strings.NewReader(output))
+	if err != nil {
+		panic(err)
+	}
+	for _, bench := range benchmarks {
+		fmt.Println(bench.Config["pkg"], bench.Name)
+		for _, unit := range bench.Units() {
+			fmt.Println(bench.Histogram(unit, &hrtime.HistogramOptions{BinCount: 5}))
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	benchmarks, err := hrbench.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(benchmarks) != 4 {
+		t.Fatalf("expected 4 benchmarks got %d", len(benchmarks))
+	}
+
+	report := benchmarks[0]
+	if report.Name != "BenchmarkReport" || report.Procs != 8 || len(report.Runs) != 3 {
+		t.Errorf("unexpected benchmark %+v", report)
+	}
+	if report.Config["goos"] != "linux" || report.Config["cpu"] != "Intel(R) Xeon(R) Processor" {
+		t.Errorf("unexpected config %v", report.Config)
+	}
+	if report.Runs[1].Iterations != 1000000 || report.Runs[1].Metrics["ns/p99"] != 2100 {
+		t.Errorf("unexpected run %+v", report.Runs[1])
+	}
+
+	tsc := benchmarks[1]
+	if units := tsc.Units(); len(units) != 1 || units[0] != "tsc" {
+		t.Errorf("expected tsc unit got %v", units)
+	}
+
+	plain, other := benchmarks[2], benchmarks[3]
+	if plain.Name != "BenchmarkPlain" || plain.Procs != 1 || plain.Runs[0].Metrics["allocs/op"] != 2 {
+		t.Errorf("unexpected benchmark %+v", plain)
+	}
+	if plain.Config["pkg"] != "github.com/loov/hrtime/hrtesting" || other.Config["pkg"] != "github.com/loov/hrtime/other" {
+		t.Errorf("expected benchmarks to be separated by pkg")
+	}
+	if units := plain.Units(); len(units) != 1 || units[0] != "ns" {
+		t.Errorf("expected only ns unit got %v", units)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"BenchmarkX 1 2",
+		"BenchmarkX abc 2 ns/op",
+		"BenchmarkX 1 abc ns/op",
+	} {
+		if _, err := hrbench.Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+
+	benchmarks, err := hrbench.Parse(strings.NewReader("Benchmarking is fun\n--- FAIL: BenchmarkX\n"))
+	if err != nil || len(benchmarks) != 0 {
+		t.Errorf("expected other lines to be ignored, got %v %v", benchmarks, err)
+	}
+}
+
+// verbose is output of `go test -bench . -v -count=2` with b.Log.
+const verbose = `goos: linux
+goarch: amd64
+pkg: example
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkFoo
+    x_test.go:3: hello
+    x_test.go:3: hello
+BenchmarkFoo-8   	     100	       176.6 ns/op
+    x_test.go:3: hello
+    x_test.go:3: hello
+BenchmarkFoo-8   	     100	       122.6 ns/op
+PASS
+ok  	example	0.003s
+`
+
+func TestParseVerbose(t *testing.T) {
+	benchmarks, err := hrbench.Parse(strings.NewReader(verbose))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(benchmarks) != 1 {
+		t.Fatalf("expected 1 benchmark got %d", len(benchmarks))
+	}
+	bench := benchmarks[0]
+	if bench.Name != "BenchmarkFoo" || len(bench.Runs) != 2 {
+		t.Errorf("unexpected benchmark %+v", bench)
+	}
+	if _, ok := bench.Config["x_test.go"]; ok || len(bench.Config) != 4 {
+		t.Errorf("unexpected config %v", bench.Config)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	benchmarks, err := hrbench.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := benchmarks[0]
+	samples := report.Samples("ns")
+	if len(samples) != 3*hrbench.SamplesPerRun {
+		t.Fatalf("expected %d samples got %d", 3*hrbench.SamplesPerRun, len(samples))
+	}
+
+	hist := report.Histogram("ns", &hrtime.HistogramOptions{BinCount: 10, Interpolation: hrtime.Linear})
+	if math.Abs(hist.P50-910) > 15 {
+		t.Errorf("expected p50 near 910 got %v", hist.P50)
+	}
+	if math.Abs(hist.P90-1110) > 20 {
+		t.Errorf("expected p90 near 1110 got %v", hist.P90)
+	}
+	if hist.Minimum != 900 || hist.Maximum != 2200 {
+		t.Errorf("expected range [900, 2200] got [%v, %v]", hist.Minimum, hist.Maximum)
+	}
+
+	plain := benchmarks[2]
+	if samples := plain.Float64s(); len(samples) != 1 || samples[0] != 20000000 {
+		t.Errorf("expected averages as samples got %v", samples)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var out strings.Builder
+	writer := hrtime.NewBenchWriter(&out)
+	writer.Percentiles = []float64{0.1, 0.5, 0.9}
+
+	samples := []float64{}
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, float64(i*10))
+	}
+	if err := writer.WriteFloat64s("Linear", "ns", samples); err != nil {
+		t.Fatal(err)
+	}
+
+	benchmarks, err := hrbench.Parse(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(benchmarks) != 1 {
+		t.Fatalf("expected 1 benchmark got %d", len(benchmarks))
+	}
+
+	quantiles := benchmarks[0].Runs[0].Quantiles("ns")
+	if len(quantiles) != 3 || quantiles[0].P != 0.1 || quantiles[2].Value != 910 {
+		t.Errorf("unexpected quantiles %v", quantiles)
+	}
+
+	cmp := hrtime.Compare(benchmarks[0], benchmarks[0])
+	if cmp.MannWhitney.Significant(0.05) {
+		t.Errorf("expected no difference")
+	}
+}
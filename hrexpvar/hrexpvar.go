@@ -0,0 +1,31 @@
+// Package hrexpvar publishes hrtime measurements as expvar variables.
+//
+// It's a separate package, because importing expvar registers /debug/vars
+// on http.DefaultServeMux and links in net/http:
+//
+//     latency := hrtime.NewConcurrentRecorder(time.Microsecond, time.Minute, 3)
+//     hrexpvar.Publish("request_latency", latency)
+package hrexpvar
+
+import (
+	"expvar"
+
+	"github.com/loov/hrtime"
+)
+
+// Publish registers rec as an expvar variable with the specified name.
+//
+// The variable serves the current statistics and bins of rec as JSON,
+// e.g. at /debug/vars. rec must be safe for concurrent use, such as
+// ConcurrentRecorder, because it's read while serving the request.
+//
+// Like expvar.Publish, it panics when the name is already registered.
+func Publish(name string, rec hrtime.Recorder) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return rec.Histogram(&hrtime.HistogramOptions{
+			BinCount:        10,
+			NiceRange:       true,
+			ClampPercentile: 0.999,
+		})
+	}))
+}
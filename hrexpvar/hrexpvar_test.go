@@ -0,0 +1,84 @@
+package hrexpvar_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/hrexpvar"
+)
+
+func ExamplePublish() {
+	latency := hrtime.NewConcurrentRecorder(time.Microsecond, time.Minute, 3)
+	hrexpvar.Publish("example_latency", latency)
+
+	// statistics are available at /debug/vars
+	_ = http.ListenAndServe("localhost:8080", nil)
+}
+
+// publishCount makes the published names unique, because expvar
+// names cannot be reused, e.g. when running with -count=2.
+var publishCount int64
+
+func TestPublish(t *testing.T) {
+	name := "test_latency_" + strconv.FormatInt(atomic.AddInt64(&publishCount, 1), 10)
+	latency := hrtime.NewConcurrentRecorder(time.Nanosecond, time.Second, 3)
+	hrexpvar.Publish(name, latency)
+	for i := 1; i <= 100; i++ {
+		latency.Observe(time.Duration(i) * time.Microsecond)
+	}
+
+	server := httptest.NewServer(expvar.Handler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var all map[string]json.RawMessage
+	if err := json.NewDecoder(response.Body).Decode(&all); err != nil {
+		t.Fatal(err)
+	}
+	var stats struct {
+		Count   int     `json:"count"`
+		Minimum float64 `json:"min"`
+		P50     float64 `json:"p50"`
+		Bins    []struct {
+			Start float64 `json:"start"`
+			Count int     `json:"count"`
+		} `json:"bins"`
+	}
+	if err := json.Unmarshal(all[name], &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Count != 100 || stats.Minimum != 1000 {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+	if stats.P50 < 49000 || stats.P50 > 52000 {
+		t.Errorf("expected p50 near 50µs got %v", stats.P50)
+	}
+	total := 0
+	for _, bin := range stats.Bins {
+		total += bin.Count
+	}
+	if total != 100 {
+		t.Errorf("expected 100 measurements in bins got %d", total)
+	}
+
+	latency.Observe(time.Millisecond)
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != 101 {
+		t.Errorf("expected live statistics, got count %d", stats.Count)
+	}
+}
@@ -0,0 +1,15 @@
+// Package hrhttp measures net/http latencies using hrtime.
+//
+// Middleware measures server side latencies per route, method and status class:
+//
+//     latency := hrhttp.Middleware(mux, nil)
+//     http.Handle("/", latency)
+//     http.Handle("/debug/latency", latency.Debug())
+//
+// Transport measures the phases of outgoing requests:
+//
+//     transport := hrhttp.NewTransport(nil, nil)
+//     client := &http.Client{Transport: transport}
+//     ...
+//     fmt.Println(transport)
+package hrhttp
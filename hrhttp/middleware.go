@@ -0,0 +1,381 @@
+package hrhttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/internal/keyed"
+)
+
+// Options is configuration for Middleware.
+type Options struct {
+	// Route returns the route of the request.
+	//
+	// By default the route is the matched pattern, when the next handler
+	// is a *http.ServeMux, and OtherRoute otherwise. Paths with identifiers
+	// should be normalized, e.g. "/user/123" to "/user/:id", otherwise
+	// every identifier gets a separate histogram.
+	Route func(r *http.Request) string
+
+	// MaxKeys is the maximum number of keys, zero uses DefaultMaxKeys.
+	// Requests with new keys after the limit are recorded under OverflowKey.
+	MaxKeys int
+
+	// Min, Max and SignificantDigits configure the recorders,
+	// see hrtime.NewLogHistogram for details. Zero values use
+	// 1µs, 1 minute and 2 significant digits.
+	Min, Max          time.Duration
+	SignificantDigits int
+}
+
+// DefaultMaxKeys is the default limit for the number of keys.
+const DefaultMaxKeys = 100
+
+var defaultOptions = Options{
+	MaxKeys:           DefaultMaxKeys,
+	Min:               time.Microsecond,
+	Max:               time.Minute,
+	SignificantDigits: 2,
+}
+
+// Key identifies a histogram of requests.
+type Key struct {
+	Route  string
+	Method string
+	// Status is the status class of the response, e.g. "2xx".
+	Status string
+}
+
+// String returns the key as "GET /route 2xx".
+func (key Key) String() string {
+	return key.Method + " " + key.Route + " " + key.Status
+}
+
+// OtherRoute is the route of requests, when the route is unknown.
+const OtherRoute = "other"
+
+// OverflowKey is the key of requests, after MaxKeys keys have been recorded.
+var OverflowKey = Key{Route: "overflow", Method: "*", Status: "*"}
+
+// Handler measures latencies of the requests served by the next handler.
+type Handler struct {
+	next      http.Handler
+	opts      Options
+	recorders *keyed.Recorders
+}
+
+// Middleware creates a handler that measures latencies of next.
+//
+// Nil opts uses the default route, at most DefaultMaxKeys keys and
+// records latencies between 1µs and 1 minute with 2 significant digits.
+func Middleware(next http.Handler, opts *Options) *Handler {
+	config := opts.withDefaults()
+	return &Handler{
+		next:      next,
+		opts:      config,
+		recorders: keyed.New(config.Min, config.Max, config.SignificantDigits, config.MaxKeys, OverflowKey),
+	}
+}
+
+// withDefaults returns a copy of opts with zero values replaced by defaults.
+func (opts *Options) withDefaults() Options {
+	if opts == nil {
+		return defaultOptions
+	}
+	result := *opts
+	if result.MaxKeys <= 0 {
+		result.MaxKeys = defaultOptions.MaxKeys
+	}
+	if result.Min == 0 {
+		result.Min = defaultOptions.Min
+	}
+	if result.Max == 0 {
+		result.Max = defaultOptions.Max
+	}
+	if result.SignificantDigits == 0 {
+		result.SignificantDigits = defaultOptions.SignificantDigits
+	}
+	return result
+}
+
+// newRecorder creates a recorder using opts.
+func (opts *Options) newRecorder() *hrtime.ConcurrentRecorder {
+	return hrtime.NewConcurrentRecorder(opts.Min, opts.Max, opts.SignificantDigits)
+}
+
+// ServeHTTP serves the request using the next handler and records its latency.
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := hrtime.Now()
+	route := handler.route(r)
+	status := &statusWriter{ResponseWriter: w}
+	handler.next.ServeHTTP(status.wrap(), r)
+	elapsed := hrtime.Since(start)
+
+	handler.recorders.Recorder(Key{
+		Route:  route,
+		Method: method(r),
+		Status: statusClass(status.status),
+	}).Observe(elapsed)
+}
+
+// route returns the route of the request.
+func (handler *Handler) route(r *http.Request) string {
+	if handler.opts.Route != nil {
+		return handler.opts.Route(r)
+	}
+	if mux, ok := handler.next.(*http.ServeMux); ok {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+	}
+	return OtherRoute
+}
+
+// method returns the method of the request, or "OTHER" for
+// non-standard methods, which would otherwise create new keys.
+func method(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return r.Method
+	default:
+		return "OTHER"
+	}
+}
+
+// Keys returns the keys of all recorded requests in sorted order.
+func (handler *Handler) Keys() []Key {
+	var keys []Key
+	for _, key := range handler.recorders.Keys() {
+		keys = append(keys, key.(Key))
+	}
+
+	sort.Slice(keys, func(i, k int) bool {
+		a, b := keys[i], keys[k]
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Status < b.Status
+	})
+	return keys
+}
+
+// Recorder returns the recorder for key or nil, when there are no such requests.
+func (handler *Handler) Recorder(key Key) *hrtime.ConcurrentRecorder {
+	return handler.recorders.Lookup(key)
+}
+
+// Debug returns a handler that serves the histograms of all keys.
+//
+// The histograms are served as text, or as JSON when the request
+// has "format=json" query parameter or accepts "application/json".
+func (handler *Handler) Debug() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			_ = handler.WriteJSON(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = handler.WriteTo(w)
+	})
+}
+
+// histograms returns histograms of all keys.
+func (handler *Handler) histograms() ([]Key, []*hrtime.Histogram) {
+	keys := handler.Keys()
+	hists := make([]*hrtime.Histogram, len(keys))
+	for i, key := range keys {
+		hists[i] = handler.Recorder(key).Histogram(keyed.HistogramOptions())
+	}
+	return keys, hists
+}
+
+// WriteTo writes histograms of all keys as text to w.
+func (handler *Handler) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	keys, hists := handler.histograms()
+	for i, key := range keys {
+		n, err := io.WriteString(w, key.String()+"\n"+hists[i].String()+"\n")
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// String returns histograms of all keys as text.
+func (handler *Handler) String() string {
+	var buffer strings.Builder
+	_, _ = handler.WriteTo(&buffer)
+	return buffer.String()
+}
+
+// keyHistogram is the JSON form of a single histogram.
+type keyHistogram struct {
+	Route     string            `json:"route"`
+	Method    string            `json:"method"`
+	Status    string            `json:"status"`
+	Histogram *hrtime.Histogram `json:"histogram"`
+}
+
+// WriteJSON writes histograms of all keys as JSON to w.
+func (handler *Handler) WriteJSON(w io.Writer) error {
+	keys, hists := handler.histograms()
+	all := make([]keyHistogram, len(keys))
+	for i, key := range keys {
+		all[i] = keyHistogram{
+			Route:     key.Route,
+			Method:    key.Method,
+			Status:    key.Status,
+			Histogram: hists[i],
+		}
+	}
+	return json.NewEncoder(w).Encode(all)
+}
+
+// statusClass returns the class of status code, e.g. "2xx".
+func statusClass(status int) string {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// statusWriter captures the status code of the response.
+//
+// http.Flusher, http.Hijacker and http.Pusher are implemented
+// only when the underlying writer implements them, see wrap.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// wrap returns a writer that implements the same optional
+// interfaces as the underlying writer.
+func (w *statusWriter) wrap() http.ResponseWriter {
+	_, flush := w.ResponseWriter.(http.Flusher)
+	_, hijack := w.ResponseWriter.(http.Hijacker)
+	_, push := w.ResponseWriter.(http.Pusher)
+	f, h, p := statusFlusher{w}, statusHijacker{w}, statusPusher{w}
+
+	switch {
+	case flush && hijack && push:
+		return struct {
+			*statusWriter
+			statusFlusher
+			statusHijacker
+			statusPusher
+		}{w, f, h, p}
+	case flush && hijack:
+		return struct {
+			*statusWriter
+			statusFlusher
+			statusHijacker
+		}{w, f, h}
+	case flush && push:
+		return struct {
+			*statusWriter
+			statusFlusher
+			statusPusher
+		}{w, f, p}
+	case hijack && push:
+		return struct {
+			*statusWriter
+			statusHijacker
+			statusPusher
+		}{w, h, p}
+	case flush:
+		return struct {
+			*statusWriter
+			statusFlusher
+		}{w, f}
+	case hijack:
+		return struct {
+			*statusWriter
+			statusHijacker
+		}{w, h}
+	case push:
+		return struct {
+			*statusWriter
+			statusPusher
+		}{w, p}
+	default:
+		return w
+	}
+}
+
+// WriteHeader captures the status code and writes it.
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes data to the response.
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// ReadFrom implements io.ReaderFrom, such that the underlying
+// writer can use an optimized copy, e.g. sendfile.
+func (w *statusWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if readerFrom, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Unwrap returns the underlying writer, which is used by http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusFlusher implements http.Flusher for statusWriter.
+type statusFlusher struct{ w *statusWriter }
+
+// Flush flushes the underlying writer.
+func (f statusFlusher) Flush() {
+	f.w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// statusHijacker implements http.Hijacker for statusWriter.
+type statusHijacker struct{ w *statusWriter }
+
+// Hijack hijacks the underlying connection.
+//
+// Hijacked requests without a status are recorded as "1xx".
+func (h statusHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && h.w.status == 0 {
+		h.w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// statusPusher implements http.Pusher for statusWriter.
+type statusPusher struct{ w *statusWriter }
+
+// Push initiates a HTTP/2 server push using the underlying writer.
+func (p statusPusher) Push(target string, opts *http.PushOptions) error {
+	return p.w.ResponseWriter.(http.Pusher).Push(target, opts)
+}
@@ -0,0 +1,329 @@
+package hrhttp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/hrhttp"
+)
+
+func ExampleMiddleware() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	latency := hrhttp.Middleware(mux, &hrhttp.Options{
+		Route: func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/user/") {
+				return "/user/:id"
+			}
+			return r.URL.Path
+		},
+	})
+	http.Handle("/", latency)
+	http.Handle("/debug/latency", latency.Debug())
+}
+
+func newTestHandler() *hrhttp.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {})
+	return hrhttp.Middleware(mux, nil)
+}
+
+func serve(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(method, target, nil))
+	return response
+}
+
+func TestMiddlewareKeys(t *testing.T) {
+	handler := newTestHandler()
+	for i := 0; i < 3; i++ {
+		serve(handler, "GET", "/")
+	}
+	serve(handler, "POST", "/")
+	serve(handler, "GET", "/missing")
+	serve(handler, "GET", "/empty")
+
+	expected := []hrhttp.Key{
+		{Route: "/", Method: "GET", Status: "2xx"},
+		{Route: "/", Method: "POST", Status: "2xx"},
+		{Route: "/empty", Method: "GET", Status: "2xx"},
+		{Route: "/missing", Method: "GET", Status: "4xx"},
+	}
+	keys := handler.Keys()
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, keys)
+	}
+	for i, key := range keys {
+		if key != expected[i] {
+			t.Errorf("key %d: expected %v got %v", i, expected[i], key)
+		}
+	}
+
+	hist := handler.Recorder(expected[0]).Histogram(&hrtime.HistogramOptions{BinCount: 1})
+	if hist.Count != 3 {
+		t.Errorf("expected 3 measurements got %d", hist.Count)
+	}
+	if handler.Recorder(hrhttp.Key{Route: "/other"}) != nil {
+		t.Errorf("expected nil recorder")
+	}
+}
+
+func TestMiddlewareRoute(t *testing.T) {
+	handler := hrhttp.Middleware(http.NotFoundHandler(), &hrhttp.Options{
+		Route: func(r *http.Request) string { return "/user/:id" },
+	})
+	serve(handler, "GET", "/user/1")
+	serve(handler, "GET", "/user/2")
+
+	keys := handler.Keys()
+	if len(keys) != 1 || keys[0].String() != "GET /user/:id 4xx" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestMiddlewareDefaultRoute(t *testing.T) {
+	handler := newTestHandler()
+	serve(handler, "GET", "/random/1")
+	serve(handler, "GET", "/random/2")
+	serve(handler, "BREW", "/")
+
+	keys := handler.Keys()
+	expected := []hrhttp.Key{
+		{Route: "/", Method: "GET", Status: "2xx"},
+		{Route: "/", Method: "OTHER", Status: "2xx"},
+	}
+	if len(keys) != len(expected) || keys[0] != expected[0] || keys[1] != expected[1] {
+		t.Errorf("expected %v got %v", expected, keys)
+	}
+
+	other := hrhttp.Middleware(http.NotFoundHandler(), nil)
+	serve(other, "GET", "/random/1")
+	serve(other, "GET", "/random/2")
+	keys = other.Keys()
+	if len(keys) != 1 || keys[0].Route != hrhttp.OtherRoute {
+		t.Errorf("expected a single %q route got %v", hrhttp.OtherRoute, keys)
+	}
+}
+
+func TestMiddlewareMaxKeys(t *testing.T) {
+	handler := hrhttp.Middleware(http.NotFoundHandler(), &hrhttp.Options{
+		Route:   func(r *http.Request) string { return r.URL.Path },
+		MaxKeys: 3,
+	})
+	for i := 0; i < 10; i++ {
+		serve(handler, "GET", "/"+strconv.Itoa(i))
+	}
+
+	keys := handler.Keys()
+	if len(keys) != 4 {
+		t.Fatalf("expected 3 keys and overflow got %v", keys)
+	}
+	overflow := handler.Recorder(hrhttp.OverflowKey)
+	if overflow == nil {
+		t.Fatalf("expected overflow key in %v", keys)
+	}
+	if n := overflow.Histogram(&hrtime.HistogramOptions{BinCount: 1}).Count; n != 7 {
+		t.Errorf("expected 7 overflow requests got %d", n)
+	}
+}
+
+func TestMiddlewareUpgrade(t *testing.T) {
+	handler := hrhttp.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		_ = rw.Flush()
+		line, _ := rw.ReadString('\n')
+		_, _ = rw.WriteString(line)
+		_ = rw.Flush()
+	}), nil)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, _ = io.WriteString(conn, "GET /echo HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101 got %d", response.StatusCode)
+	}
+
+	_, _ = io.WriteString(conn, "ping\n")
+	if line, err := reader.ReadString('\n'); err != nil || line != "ping\n" {
+		t.Fatalf("expected echo got %q %v", line, err)
+	}
+	_ = conn.Close()
+
+	// the request is recorded after the handler returns
+	for i := 0; i < 100 && len(handler.Keys()) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	keys := handler.Keys()
+	if len(keys) != 1 || keys[0].Status != "1xx" {
+		t.Errorf("expected upgraded request got %v", keys)
+	}
+}
+
+// plainWriter is a http.ResponseWriter without optional interfaces.
+type plainWriter struct{ http.ResponseWriter }
+
+func TestMiddlewareWriterInterfaces(t *testing.T) {
+	var flush bool
+	handler := hrhttp.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, flush = w.(http.Flusher)
+		if _, ok := w.(http.Hijacker); ok {
+			t.Errorf("expected no http.Hijacker")
+		}
+		if _, ok := w.(http.Pusher); ok {
+			t.Errorf("expected no http.Pusher")
+		}
+		if _, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok {
+			t.Errorf("expected Unwrap")
+		}
+		if _, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello")); err != nil {
+			t.Error(err)
+		}
+	}), nil)
+
+	response := serve(handler, "GET", "/")
+	if !flush {
+		t.Errorf("expected http.Flusher of httptest.ResponseRecorder")
+	}
+	if response.Body.String() != "hello" {
+		t.Errorf("expected body got %q", response.Body.String())
+	}
+	if keys := handler.Keys(); len(keys) != 1 || keys[0].Status != "2xx" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(plainWriter{response}, httptest.NewRequest("GET", "/", nil))
+	if flush {
+		t.Errorf("expected no http.Flusher")
+	}
+}
+
+func TestMiddlewareConcurrent(t *testing.T) {
+	handler := newTestHandler()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				serve(handler, "GET", "/")
+			}
+		}()
+	}
+	wg.Wait()
+
+	hist := handler.Recorder(hrhttp.Key{Route: "/", Method: "GET", Status: "2xx"}).Histogram(&hrtime.HistogramOptions{BinCount: 1})
+	if hist.Count != 800 {
+		t.Errorf("expected 800 measurements got %d", hist.Count)
+	}
+}
+
+func TestDebugText(t *testing.T) {
+	handler := newTestHandler()
+	serve(handler, "GET", "/")
+	serve(handler, "GET", "/missing")
+
+	server := httptest.NewServer(handler.Debug())
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := string(body)
+	if text != handler.String() {
+		t.Errorf("expected:\n%s\ngot:\n%s", handler.String(), text)
+	}
+	if !strings.Contains(text, "GET / 2xx\n") || !strings.Contains(text, "GET /missing 4xx\n") {
+		t.Errorf("missing keys:\n%s", text)
+	}
+	if !strings.Contains(text, "avg") {
+		t.Errorf("missing histogram:\n%s", text)
+	}
+}
+
+func TestDebugJSON(t *testing.T) {
+	handler := newTestHandler()
+	serve(handler, "GET", "/")
+	serve(handler, "GET", "/")
+
+	for _, request := range []*http.Request{
+		httptest.NewRequest("GET", "/debug?format=json", nil),
+		func() *http.Request {
+			r := httptest.NewRequest("GET", "/debug", nil)
+			r.Header.Set("Accept", "application/json")
+			return r
+		}(),
+	} {
+		response := httptest.NewRecorder()
+		handler.Debug().ServeHTTP(response, request)
+		if response.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", response.Header().Get("Content-Type"))
+		}
+
+		var histograms []struct {
+			Route     string `json:"route"`
+			Method    string `json:"method"`
+			Status    string `json:"status"`
+			Histogram struct {
+				Count int `json:"count"`
+			} `json:"histogram"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&histograms); err != nil {
+			t.Fatal(err)
+		}
+		if len(histograms) != 1 {
+			t.Fatalf("expected 1 histogram got %d", len(histograms))
+		}
+		got := histograms[0]
+		if got.Route != "/" || got.Method != "GET" || got.Status != "2xx" || got.Histogram.Count != 2 {
+			t.Errorf("unexpected histogram %+v", got)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package hrhttp
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/internal/keyed"
+)
+
+// Phase is a part of an outgoing request.
+type Phase int
+
+const (
+	// DNS is the duration of the host lookup.
+	DNS Phase = iota
+	// Connect is the duration of establishing the connection.
+	Connect
+	// TLS is the duration of the TLS handshake.
+	TLS
+	// FirstByte is the duration from writing the request to the first response byte.
+	FirstByte
+	// Body is the duration of reading the response body.
+	Body
+	// Total is the duration from starting the request to reading the whole body.
+	Total
+
+	phaseCount
+)
+
+// Phases lists all the phases in the order they happen.
+var Phases = []Phase{DNS, Connect, TLS, FirstByte, Body, Total}
+
+// String returns the name of the phase.
+func (phase Phase) String() string {
+	switch phase {
+	case DNS:
+		return "dns"
+	case Connect:
+		return "connect"
+	case TLS:
+		return "tls"
+	case FirstByte:
+		return "first-byte"
+	case Body:
+		return "body"
+	case Total:
+		return "total"
+	default:
+		return "Phase(" + strconv.Itoa(int(phase)) + ")"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (phase Phase) MarshalText() ([]byte, error) {
+	return []byte(phase.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (phase *Phase) UnmarshalText(text []byte) error {
+	for _, p := range Phases {
+		if p.String() == string(text) {
+			*phase = p
+			return nil
+		}
+	}
+	return errors.New("unknown phase " + strconv.Quote(string(text)))
+}
+
+// Transport is a http.RoundTripper that measures the phases of the requests.
+//
+// DNS, Connect and TLS are only recorded when the request uses a new
+// connection. Body and Total are recorded when the body has been read
+// to the end or closed.
+type Transport struct {
+	base      http.RoundTripper
+	recorders [phaseCount]*hrtime.ConcurrentRecorder
+}
+
+// NewTransport creates a transport that measures the requests made
+// using base. Nil base uses http.DefaultTransport.
+//
+// Route in opts is ignored.
+func NewTransport(base http.RoundTripper, opts *Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	config := opts.withDefaults()
+
+	transport := &Transport{base: base}
+	for i := range transport.recorders {
+		transport.recorders[i] = config.newRecorder()
+	}
+	return transport
+}
+
+// RoundTrip executes the request using the base transport and records its phases.
+func (transport *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	trace := &requestTrace{
+		transport: transport,
+		start:     hrtime.Now(),
+	}
+	r = r.WithContext(httptrace.WithClientTrace(r.Context(), trace.clientTrace()))
+
+	response, err := transport.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	response.Body = &timedBody{
+		ReadCloser: response.Body,
+		trace:      trace,
+		start:      hrtime.Now(),
+	}
+	return response, nil
+}
+
+// observe records the duration of phase.
+func (transport *Transport) observe(phase Phase, d time.Duration) {
+	transport.recorders[phase].Observe(d)
+}
+
+// Recorder returns the recorder for phase.
+func (transport *Transport) Recorder(phase Phase) *hrtime.ConcurrentRecorder {
+	return transport.recorders[phase]
+}
+
+// Histogram returns the histogram of phase.
+func (transport *Transport) Histogram(phase Phase, opts *hrtime.HistogramOptions) *hrtime.Histogram {
+	return transport.recorders[phase].Histogram(opts)
+}
+
+// histograms returns histograms of the phases that have measurements.
+func (transport *Transport) histograms() ([]Phase, []*hrtime.Histogram) {
+	var phases []Phase
+	var hists []*hrtime.Histogram
+	for _, phase := range Phases {
+		snapshot := transport.recorders[phase].Snapshot()
+		if snapshot.Count() == 0 {
+			continue
+		}
+		phases = append(phases, phase)
+		hists = append(hists, snapshot.Histogram(keyed.HistogramOptions()))
+	}
+	return phases, hists
+}
+
+// WriteTo writes histograms of all measured phases as text to w.
+func (transport *Transport) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	phases, hists := transport.histograms()
+	for i, phase := range phases {
+		n, err := io.WriteString(w, phase.String()+"\n"+hists[i].String()+"\n")
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// String returns histograms of all measured phases as text.
+func (transport *Transport) String() string {
+	var buffer strings.Builder
+	_, _ = transport.WriteTo(&buffer)
+	return buffer.String()
+}
+
+// phaseHistogram is the JSON form of a single phase.
+type phaseHistogram struct {
+	Phase     Phase             `json:"phase"`
+	Histogram *hrtime.Histogram `json:"histogram"`
+}
+
+// WriteJSON writes histograms of all measured phases as JSON to w.
+func (transport *Transport) WriteJSON(w io.Writer) error {
+	phases, hists := transport.histograms()
+	all := make([]phaseHistogram, len(phases))
+	for i, phase := range phases {
+		all[i] = phaseHistogram{Phase: phase, Histogram: hists[i]}
+	}
+	return json.NewEncoder(w).Encode(all)
+}
+
+// requestTrace tracks the phases of a single request.
+//
+// The callbacks may be called from different goroutines.
+type requestTrace struct {
+	transport *Transport
+	start     time.Duration
+
+	mu       sync.Mutex
+	dnsStart time.Duration
+	tlsStart time.Duration
+	wrote    time.Duration
+	// connectStarts contains the start of each connection attempt by
+	// network and address, because dialing with Happy Eyeballs makes
+	// several attempts concurrently.
+	connectStarts map[string]time.Duration
+}
+
+// clientTrace returns the hooks that record the phases.
+func (trace *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			trace.mark(&trace.dnsStart)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if info.Err == nil {
+				trace.since(DNS, &trace.dnsStart)
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			now := hrtime.Now()
+			trace.mu.Lock()
+			if trace.connectStarts == nil {
+				trace.connectStarts = map[string]time.Duration{}
+			}
+			trace.connectStarts[network+" "+addr] = now
+			trace.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			now := hrtime.Now()
+			trace.mu.Lock()
+			start, ok := trace.connectStarts[network+" "+addr]
+			delete(trace.connectStarts, network+" "+addr)
+			trace.mu.Unlock()
+			if ok && err == nil {
+				trace.transport.observe(Connect, now-start)
+			}
+		},
+		TLSHandshakeStart: func() {
+			trace.mark(&trace.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				trace.since(TLS, &trace.tlsStart)
+			}
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			trace.mark(&trace.wrote)
+		},
+		GotFirstResponseByte: func() {
+			trace.mu.Lock()
+			wrote := trace.wrote
+			trace.mu.Unlock()
+			if wrote == 0 {
+				wrote = trace.start
+			}
+			trace.transport.observe(FirstByte, hrtime.Since(wrote))
+		},
+	}
+}
+
+// mark stores the current time in start.
+func (trace *requestTrace) mark(start *time.Duration) {
+	now := hrtime.Now()
+	trace.mu.Lock()
+	*start = now
+	trace.mu.Unlock()
+}
+
+// since records the duration from start, when it has been marked.
+func (trace *requestTrace) since(phase Phase, start *time.Duration) {
+	now := hrtime.Now()
+	trace.mu.Lock()
+	from := *start
+	*start = 0
+	trace.mu.Unlock()
+	if from != 0 {
+		trace.transport.observe(phase, now-from)
+	}
+}
+
+// timedBody records Body and Total when the body has been read or closed.
+type timedBody struct {
+	io.ReadCloser
+	trace *requestTrace
+	start time.Duration
+	once  sync.Once
+}
+
+// Read reads from the body.
+func (body *timedBody) Read(data []byte) (int, error) {
+	n, err := body.ReadCloser.Read(data)
+	if err == io.EOF {
+		body.done()
+	}
+	return n, err
+}
+
+// Close closes the body.
+func (body *timedBody) Close() error {
+	body.done()
+	return body.ReadCloser.Close()
+}
+
+// done records Body and Total once.
+func (body *timedBody) done() {
+	body.once.Do(func() {
+		now := hrtime.Now()
+		body.trace.transport.observe(Body, now-body.start)
+		body.trace.transport.observe(Total, now-body.trace.start)
+	})
+}
@@ -0,0 +1,197 @@
+package hrhttp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/hrhttp"
+)
+
+func ExampleTransport() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	transport := hrhttp.NewTransport(nil, nil)
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 100; i++ {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			panic(err)
+		}
+		_, _ = ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+	}
+
+	fmt.Println(transport)
+}
+
+func get(t *testing.T, client *http.Client, url string) {
+	t.Helper()
+	response, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(response.Body); err != nil {
+		t.Fatal(err)
+	}
+	if err := response.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func count(transport *hrhttp.Transport, phase hrhttp.Phase) int {
+	return transport.Recorder(phase).Histogram(&hrtime.HistogramOptions{BinCount: 1}).Count
+}
+
+func TestTransportPhases(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 1<<16))
+	}))
+	defer server.Close()
+
+	// use "localhost" such that the request does a lookup
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	base := server.Client().Transport.(*http.Transport)
+	base.TLSClientConfig.ServerName = "example.com"
+
+	transport := hrhttp.NewTransport(base, nil)
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 5; i++ {
+		get(t, client, url)
+	}
+
+	// the connection is reused
+	for _, phase := range []hrhttp.Phase{hrhttp.DNS, hrhttp.Connect, hrhttp.TLS} {
+		if n := count(transport, phase); n != 1 {
+			t.Errorf("%v: expected 1 measurement got %d", phase, n)
+		}
+	}
+	for _, phase := range []hrhttp.Phase{hrhttp.FirstByte, hrhttp.Body, hrhttp.Total} {
+		if n := count(transport, phase); n != 5 {
+			t.Errorf("%v: expected 5 measurements got %d", phase, n)
+		}
+	}
+}
+
+func TestTransportHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	transport := hrhttp.NewTransport(nil, nil)
+	client := &http.Client{Transport: transport}
+	get(t, client, server.URL)
+
+	text := transport.String()
+	if strings.Contains(text, "tls\n") {
+		t.Errorf("unexpected tls phase:\n%s", text)
+	}
+	for _, phase := range []string{"connect\n", "first-byte\n", "body\n", "total\n"} {
+		if !strings.Contains(text, phase) {
+			t.Errorf("missing %q:\n%s", phase, text)
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := transport.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var phases []struct {
+		Phase     hrhttp.Phase `json:"phase"`
+		Histogram struct {
+			Count int `json:"count"`
+		} `json:"histogram"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &phases); err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) == 0 || phases[len(phases)-1].Phase != hrhttp.Total || phases[len(phases)-1].Histogram.Count != 1 {
+		t.Errorf("unexpected phases %+v", phases)
+	}
+}
+
+func TestTransportClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 1<<20))
+	}))
+	defer server.Close()
+
+	transport := hrhttp.NewTransport(nil, nil)
+	response, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	_ = response.Body.Close()
+
+	if n := count(transport, hrhttp.Body); n != 1 {
+		t.Errorf("expected 1 measurement got %d", n)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	transport := hrhttp.NewTransport(nil, nil)
+	if _, err := (&http.Client{Transport: transport}).Get(server.URL); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := count(transport, hrhttp.Total); n != 0 {
+		t.Errorf("expected no measurements got %d", n)
+	}
+}
+
+func TestPhaseText(t *testing.T) {
+	for _, phase := range hrhttp.Phases {
+		text, _ := phase.MarshalText()
+		var decoded hrhttp.Phase
+		if err := decoded.UnmarshalText(text); err != nil || decoded != phase {
+			t.Errorf("%v: got %v %v", phase, decoded, err)
+		}
+	}
+	var phase hrhttp.Phase
+	if err := phase.UnmarshalText([]byte("unknown")); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+// traceTransport calls the trace hooks of concurrent connection attempts.
+type traceTransport struct{}
+
+func (traceTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	trace := httptrace.ContextClientTrace(r.Context())
+	trace.ConnectStart("tcp", "[::1]:80")
+	time.Sleep(2 * time.Millisecond)
+	trace.ConnectStart("tcp", "127.0.0.1:80")
+	trace.ConnectDone("tcp", "[::1]:80", nil)
+	trace.ConnectDone("tcp", "127.0.0.1:80", errors.New("canceled"))
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestTransportConcurrentConnect(t *testing.T) {
+	transport := hrhttp.NewTransport(traceTransport{}, nil)
+	get(t, &http.Client{Transport: transport}, "http://localhost/")
+
+	hist := transport.Histogram(hrhttp.Connect, &hrtime.HistogramOptions{BinCount: 1})
+	if hist.Count != 1 {
+		t.Fatalf("expected 1 connection got %d", hist.Count)
+	}
+	if hist.Minimum < float64(2*time.Millisecond) {
+		t.Errorf("expected the duration of the first attempt got %v", time.Duration(hist.Minimum))
+	}
+}
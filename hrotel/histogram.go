@@ -0,0 +1,142 @@
+package hrotel
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+// metrics is OTLP MetricsData.
+type metrics struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+// metric is OTLP Metric with an exponential histogram.
+type metric struct {
+	Name                 string               `json:"name"`
+	Unit                 string               `json:"unit"`
+	ExponentialHistogram exponentialHistogram `json:"exponentialHistogram"`
+}
+
+type exponentialHistogram struct {
+	DataPoints             []exponentialDataPoint `json:"dataPoints"`
+	AggregationTemporality int                    `json:"aggregationTemporality"`
+}
+
+// aggregationTemporalityCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE.
+const aggregationTemporalityCumulative = 2
+
+// exponentialDataPoint is OTLP ExponentialHistogramDataPoint.
+type exponentialDataPoint struct {
+	TimeUnixNano string  `json:"timeUnixNano"`
+	Count        string  `json:"count"`
+	Sum          float64 `json:"sum"`
+	Scale        int     `json:"scale"`
+	ZeroCount    string  `json:"zeroCount"`
+	Positive     buckets `json:"positive"`
+	Min          float64 `json:"min"`
+	Max          float64 `json:"max"`
+}
+
+type buckets struct {
+	Offset       int      `json:"offset"`
+	BucketCounts []string `json:"bucketCounts"`
+}
+
+// WriteHistogram writes hist as an OTLP exponential histogram metric in nanoseconds to w.
+//
+// The scale is chosen such that the measurements fit into MaxBuckets buckets.
+// Divided histograms are exported in the original nanoseconds.
+func (exp *Exporter) WriteHistogram(w io.Writer, name string, hist *hrtime.Histogram) error {
+	divisor := hist.Divisor()
+	point := exponentialDataPoint{
+		TimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		Count:        strconv.Itoa(hist.Count),
+		Sum:          hist.Average * float64(hist.Count) * divisor,
+		Min:          hist.Minimum * divisor,
+		Max:          hist.Maximum * divisor,
+	}
+	var zeroCount int
+	point.Scale, point.Positive, zeroCount = exponentialBuckets(hist, divisor, exp.MaxBuckets)
+	point.ZeroCount = strconv.Itoa(zeroCount)
+
+	return writeJSON(w, metrics{
+		ResourceMetrics: []resourceMetrics{{
+			Resource: exp.resource(),
+			ScopeMetrics: []scopeMetrics{{
+				Scope: scope{Name: scopeName},
+				Metrics: []metric{{
+					Name: name,
+					Unit: "ns",
+					ExponentialHistogram: exponentialHistogram{
+						DataPoints:             []exponentialDataPoint{point},
+						AggregationTemporality: aggregationTemporalityCumulative,
+					},
+				}},
+			}},
+		}},
+	})
+}
+
+// exponentialBuckets distributes the measurements multiplied by divisor into
+// base 2^(2^-scale) buckets, where bucket index i contains values in (base^i, base^(i+1)].
+func exponentialBuckets(hist *hrtime.Histogram, divisor float64, maxBuckets int) (scale int, positive buckets, zeroCount int) {
+	if maxBuckets <= 0 {
+		maxBuckets = 160
+	}
+
+	zeroCount = hist.Cumulative([]float64{0})[0]
+	positive.BucketCounts = []string{}
+	if hist.Count == zeroCount {
+		return 0, positive, zeroCount
+	}
+
+	minimum := hist.Minimum * divisor
+	if minimum <= 0 {
+		minimum = 1
+	}
+	maximum := math.Max(hist.Maximum*divisor, minimum)
+
+	index := func(v float64, scale int) int {
+		return int(math.Ceil(math.Log2(v)*math.Exp2(float64(scale)))) - 1
+	}
+	for scale = 20; scale > -10; scale-- {
+		if index(maximum, scale)-index(minimum, scale)+1 <= maxBuckets {
+			break
+		}
+	}
+
+	first, last := index(minimum, scale), index(maximum, scale)
+	bounds := make([]float64, last-first+1)
+	for i := range bounds {
+		bounds[i] = math.Exp2(float64(first+i+1) * math.Exp2(-float64(scale)))
+	}
+	bounds[len(bounds)-1] = math.Max(bounds[len(bounds)-1], maximum)
+
+	// Cumulative uses the divided values
+	divided := make([]float64, len(bounds))
+	for i, bound := range bounds {
+		divided[i] = bound / divisor
+	}
+	cumulative := hist.Cumulative(divided)
+	previous := zeroCount
+	for _, count := range cumulative {
+		positive.BucketCounts = append(positive.BucketCounts, strconv.Itoa(count-previous))
+		previous = count
+	}
+	positive.Offset = first
+	return scale, positive, zeroCount
+}
@@ -0,0 +1,203 @@
+// Package hrotel exports hrtime measurements as OpenTelemetry OTLP/JSON.
+//
+// Stopwatch spans are exported as OTLP traces and histograms as OTLP
+// exponential histogram metrics. The output can be sent to a collector
+// using the OTLP/HTTP JSON encoding or stored in a file:
+//
+//     exporter := hrotel.NewExporter("my-service")
+//     err := exporter.WriteSpans(file, "request", stopwatch)
+package hrotel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+// scopeName is the instrumentation scope of the exported data.
+const scopeName = "github.com/loov/hrtime"
+
+// Exporter converts hrtime measurements into OTLP/JSON.
+type Exporter struct {
+	// ServiceName is the service.name resource attribute.
+	ServiceName string
+	// Offset converts hrtime.Now values into Unix time in nanoseconds.
+	Offset time.Duration
+	// MaxBuckets is the maximum number of buckets in exponential histograms.
+	MaxBuckets int
+}
+
+// NewExporter creates an exporter for the service.
+//
+// The offset from hrtime.Now to wall-clock time is measured once,
+// hence spans must be measured in the same process.
+func NewExporter(serviceName string) *Exporter {
+	return &Exporter{
+		ServiceName: serviceName,
+		Offset:      time.Duration(time.Now().UnixNano()) - hrtime.Now(),
+		MaxBuckets:  160,
+	}
+}
+
+// resource is OTLP Resource.
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+// scope is OTLP InstrumentationScope.
+type scope struct {
+	Name string `json:"name"`
+}
+
+// keyValue is OTLP KeyValue with a string value.
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue is OTLP AnyValue.
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func stringAttribute(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) keyValue {
+	formatted := strconv.FormatInt(value, 10)
+	return keyValue{Key: key, Value: anyValue{IntValue: &formatted}}
+}
+
+// resource returns the resource of the exported data.
+func (exp *Exporter) resource() resource {
+	return resource{
+		Attributes: []keyValue{stringAttribute("service.name", exp.ServiceName)},
+	}
+}
+
+// unixNano converts hrtime.Now value into Unix time in nanoseconds.
+func (exp *Exporter) unixNano(t time.Duration) string {
+	return strconv.FormatInt(int64(t+exp.Offset), 10)
+}
+
+// traces is OTLP TracesData.
+type traces struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+// span is OTLP Span.
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+}
+
+// spanKindInternal is SPAN_KIND_INTERNAL.
+const spanKindInternal = 1
+
+// WriteSpans writes the spans of the stopwatch as a single OTLP trace to w.
+//
+// The trace has a root span with the name covering all the laps.
+// Each lap is a child span named "lap" with the lap number as an attribute,
+// and phases are children of the lap spans.
+func (exp *Exporter) WriteSpans(w io.Writer, name string, bench *hrtime.Stopwatch) error {
+	laps := bench.Spans()
+	traceID := randomID(16)
+
+	root := span{
+		TraceID: traceID,
+		SpanID:  randomID(8),
+		Name:    name,
+		Kind:    spanKindInternal,
+	}
+	start, finish := laps[0].Start, laps[0].Finish
+	for _, lap := range laps {
+		if lap.Start < start {
+			start = lap.Start
+		}
+		if lap.Finish > finish {
+			finish = lap.Finish
+		}
+	}
+	root.StartTimeUnixNano = exp.unixNano(start)
+	root.EndTimeUnixNano = exp.unixNano(finish)
+
+	spans := []span{root}
+	lapIDs := make([]string, len(laps))
+	for i, lap := range laps {
+		lapIDs[i] = randomID(8)
+		spans = append(spans, span{
+			TraceID:           traceID,
+			SpanID:            lapIDs[i],
+			ParentSpanID:      root.SpanID,
+			Name:              "lap",
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: exp.unixNano(lap.Start),
+			EndTimeUnixNano:   exp.unixNano(lap.Finish),
+			Attributes:        []keyValue{intAttribute("lap", int64(i))},
+		})
+	}
+
+	for _, phase := range bench.Phases() {
+		attributes := []keyValue{intAttribute("lap", int64(phase.Lap))}
+		for _, attr := range phase.Attributes {
+			attributes = append(attributes, stringAttribute(attr.Key, attr.Value))
+		}
+		spans = append(spans, span{
+			TraceID:           traceID,
+			SpanID:            randomID(8),
+			ParentSpanID:      lapIDs[phase.Lap],
+			Name:              phase.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: exp.unixNano(phase.Start),
+			EndTimeUnixNano:   exp.unixNano(phase.Finish),
+			Attributes:        attributes,
+		})
+	}
+
+	return writeJSON(w, traces{
+		ResourceSpans: []resourceSpans{{
+			Resource: exp.resource(),
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName},
+				Spans: spans,
+			}},
+		}},
+	})
+}
+
+// randomID returns a random hex encoded identifier of n bytes.
+func randomID(n int) string {
+	id := make([]byte, n)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// writeJSON writes v as a single line of JSON to w.
+func writeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,221 @@
+package hrotel_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/hrotel"
+)
+
+func ExampleExporter() {
+	bench := hrtime.NewStopwatch(8)
+	for lap := bench.Start(); lap >= 0; lap = bench.Start() {
+		bench.Phase(lap, "work")
+		bench.Stop(lap)
+	}
+
+	exporter := hrotel.NewExporter("example")
+	_ = exporter.WriteSpans(os.Stdout, "benchmark", bench)
+	_ = exporter.WriteHistogram(os.Stdout, "latency", bench.Histogram(10))
+}
+
+type traces struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []struct {
+				Key   string
+				Value struct{ StringValue string }
+			}
+		}
+		ScopeSpans []struct {
+			Spans []struct {
+				TraceID           string
+				SpanID            string
+				ParentSpanID      string
+				Name              string
+				StartTimeUnixNano string
+				EndTimeUnixNano   string
+				Attributes        []struct {
+					Key   string
+					Value struct{ StringValue, IntValue string }
+				}
+			}
+		}
+	}
+}
+
+func TestWriteSpans(t *testing.T) {
+	bench := hrtime.NewStopwatch(4)
+	for lap := bench.Start(); lap >= 0; lap = bench.Start() {
+		bench.Phase(lap, "decode", hrtime.Attribute{Key: "format", Value: "json"})
+		bench.Phase(lap, "handle")
+		bench.Stop(lap)
+	}
+
+	exporter := hrotel.NewExporter("test")
+	var buffer bytes.Buffer
+	if err := exporter.WriteSpans(&buffer, "requests", bench); err != nil {
+		t.Fatal(err)
+	}
+
+	var data traces
+	if err := json.Unmarshal(buffer.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "test" {
+		t.Errorf("expected service name")
+	}
+
+	spans := data.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1+4+8 {
+		t.Fatalf("expected 13 spans got %d", len(spans))
+	}
+
+	root := spans[0]
+	if root.Name != "requests" || root.ParentSpanID != "" || len(root.TraceID) != 32 || len(root.SpanID) != 16 {
+		t.Errorf("unexpected root %+v", root)
+	}
+
+	ids := map[string]string{}
+	for _, span := range spans {
+		ids[span.SpanID] = span.Name
+		if span.TraceID != root.TraceID {
+			t.Errorf("expected single trace")
+		}
+		start, _ := strconv.ParseInt(span.StartTimeUnixNano, 10, 64)
+		end, _ := strconv.ParseInt(span.EndTimeUnixNano, 10, 64)
+		if start <= 0 || end < start {
+			t.Errorf("invalid time range %+v", span)
+		}
+	}
+	for _, span := range spans[1:] {
+		parent := ids[span.ParentSpanID]
+		if span.Name == "lap" && parent != "requests" || span.Name != "lap" && parent != "lap" {
+			t.Errorf("unexpected parent %q for %q", parent, span.Name)
+		}
+	}
+
+	decode := spans[5]
+	if decode.Name != "decode" || decode.Attributes[1].Key != "format" || decode.Attributes[1].Value.StringValue != "json" {
+		t.Errorf("unexpected phase %+v", decode)
+	}
+}
+
+type metrics struct {
+	ResourceMetrics []struct {
+		ScopeMetrics []struct {
+			Metrics []struct {
+				Name                 string
+				Unit                 string
+				ExponentialHistogram struct {
+					AggregationTemporality int
+					DataPoints             []struct {
+						Count     string
+						Sum       float64
+						Scale     int
+						ZeroCount string
+						Min, Max  float64
+						Positive  struct {
+							Offset       int
+							BucketCounts []string
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWriteHistogram(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{1, 2, 3, 4}, &hrtime.HistogramOptions{BinCount: 2})
+
+	exporter := hrotel.NewExporter("test")
+	exporter.MaxBuckets = 3
+
+	var buffer bytes.Buffer
+	if err := exporter.WriteHistogram(&buffer, "latency", hist); err != nil {
+		t.Fatal(err)
+	}
+
+	var data metrics
+	if err := json.Unmarshal(buffer.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	metric := data.ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+	if metric.Name != "latency" || metric.Unit != "ns" || metric.ExponentialHistogram.AggregationTemporality != 2 {
+		t.Errorf("unexpected metric %+v", metric)
+	}
+
+	point := metric.ExponentialHistogram.DataPoints[0]
+	if point.Count != "4" || point.Sum != 10 || point.ZeroCount != "0" || point.Min != 1 || point.Max != 4 {
+		t.Errorf("unexpected data point %+v", point)
+	}
+	if point.Scale != 0 || point.Positive.Offset != -1 {
+		t.Errorf("expected scale 0 and offset -1, got %d and %d", point.Scale, point.Positive.Offset)
+	}
+	expected := []string{"1", "1", "2"}
+	if !reflect.DeepEqual(point.Positive.BucketCounts, expected) {
+		t.Errorf("expected buckets %v got %v", expected, point.Positive.BucketCounts)
+	}
+}
+
+func TestWriteHistogramScale(t *testing.T) {
+	samples := []float64{}
+	for i := 1; i <= 1000; i++ {
+		samples = append(samples, float64(i*1000))
+	}
+	hist := hrtime.NewHistogram(samples, &hrtime.HistogramOptions{BinCount: 10})
+
+	var buffer bytes.Buffer
+	if err := hrotel.NewExporter("test").WriteHistogram(&buffer, "latency", hist); err != nil {
+		t.Fatal(err)
+	}
+
+	var data metrics
+	if err := json.Unmarshal(buffer.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	point := data.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].ExponentialHistogram.DataPoints[0]
+	if len(point.Positive.BucketCounts) > 160 {
+		t.Errorf("expected at most 160 buckets got %d", len(point.Positive.BucketCounts))
+	}
+
+	total := 0
+	for _, count := range point.Positive.BucketCounts {
+		n, _ := strconv.Atoi(count)
+		total += n
+	}
+	if total != 1000 {
+		t.Errorf("expected 1000 measurements in buckets got %d", total)
+	}
+}
+
+func TestWriteHistogramDivided(t *testing.T) {
+	samples := []float64{100, 200, 300, 400, 5000}
+	opts := &hrtime.HistogramOptions{BinCount: 2}
+	hist := hrtime.NewHistogram(samples, opts)
+	divided := hrtime.NewHistogram(samples, opts)
+	divided.Divide(10)
+
+	point := func(hist *hrtime.Histogram) interface{} {
+		var buffer bytes.Buffer
+		if err := hrotel.NewExporter("test").WriteHistogram(&buffer, "latency", hist); err != nil {
+			t.Fatal(err)
+		}
+		var data metrics
+		if err := json.Unmarshal(buffer.Bytes(), &data); err != nil {
+			t.Fatal(err)
+		}
+		point := data.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].ExponentialHistogram.DataPoints[0]
+		return point
+	}
+
+	if expected, got := point(hist), point(divided); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %+v got %+v", expected, got)
+	}
+}
@@ -0,0 +1,264 @@
+// Package hrprom exposes hrtime measurements in Prometheus text exposition format.
+//
+// Measurements are in nanoseconds, however they are exposed in seconds
+// as recommended by Prometheus:
+//
+//     latency := hrtime.NewConcurrentRecorder(time.Microsecond, time.Minute, 3)
+//
+//     registry := hrprom.NewRegistry()
+//     registry.Register("http_request_duration_seconds", "Request latency.",
+//         hrprom.Labels{"route": "/"}, latency, nil)
+//     http.Handle("/metrics", registry)
+package hrprom
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/loov/hrtime"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Source provides a histogram of measurements in nanoseconds.
+//
+// hrtime.ConcurrentRecorder, hrtime.LogHistogram and other recorders implement Source.
+type Source interface {
+	Histogram(opts *hrtime.HistogramOptions) *hrtime.Histogram
+}
+
+// Labels are the labels of a metric.
+type Labels map[string]string
+
+var metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// DefaultBuckets are powers of two seconds from ~1µs to ~16s,
+// which match native histogram schema 0.
+var DefaultBuckets = ExponentialBuckets(0, 1e-6, 16)
+
+// ExponentialBuckets returns bucket bounds in seconds between min and max that match
+// the bucket boundaries of Prometheus native histograms with the specified schema.
+//
+// Schema must be between -4 and 8, each power of two is divided into 2^schema buckets.
+// The bounds start at the largest boundary at or below min and end at the smallest
+// boundary at or above max.
+func ExponentialBuckets(schema int, min, max float64) []float64 {
+	if schema < -4 || schema > 8 {
+		panic("schema must be between -4 and 8")
+	}
+	if min <= 0 || max < min {
+		panic("invalid bucket range")
+	}
+
+	step := math.Exp2(-float64(schema))
+	first := math.Floor(math.Log2(min) / step)
+	last := math.Ceil(math.Log2(max) / step)
+
+	bounds := make([]float64, 0, int(last-first)+1)
+	for i := first; i <= last; i++ {
+		bounds = append(bounds, math.Exp2(i*step))
+	}
+	return bounds
+}
+
+// WriteHistogram writes hist as a Prometheus histogram with bucket bounds in seconds.
+//
+// When buckets is nil, the bins of the histogram are used as buckets.
+func WriteHistogram(w io.Writer, name, help string, labels Labels, hist *hrtime.Histogram, buckets []float64) error {
+	mustBeValid(name, labels, buckets)
+	if buckets == nil {
+		buckets = binBuckets(hist)
+	}
+
+	var b strings.Builder
+	writeHeader(&b, name, help)
+	writeHistogram(&b, name, labels, hist, buckets)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// binBuckets returns the bucket bounds in seconds at the ends of the histogram bins.
+func binBuckets(hist *hrtime.Histogram) []float64 {
+	var buckets []float64
+	for _, bin := range hist.Bins[1:] {
+		buckets = append(buckets, bin.Start/1e9)
+	}
+	return buckets
+}
+
+// Registry contains histograms for exposition.
+//
+// Registry implements http.Handler, which serves all the histograms.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+// metric is a single registered histogram.
+type metric struct {
+	name    string
+	help    string
+	labels  Labels
+	source  Source
+	buckets []float64
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds source to the registry as histogram with the name and labels.
+//
+// Buckets are the upper bounds of the buckets in seconds in ascending order.
+// When buckets is nil, DefaultBuckets is used.
+//
+// Histograms with the same name must have different labels and
+// they share the help of the first registered histogram.
+func (reg *Registry) Register(name, help string, labels Labels, source Source, buckets []float64) {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	mustBeValid(name, labels, buckets)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for _, m := range reg.metrics {
+		if m.name == name && formatLabels(m.labels, "") == formatLabels(labels, "") {
+			panic("histogram " + name + formatLabels(labels, "") + " already registered")
+		}
+	}
+	reg.metrics = append(reg.metrics, &metric{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		source:  source,
+		buckets: buckets,
+	})
+}
+
+// mustBeValid checks name, labels and buckets.
+func mustBeValid(name string, labels Labels, buckets []float64) {
+	if !metricName.MatchString(name) {
+		panic("invalid metric name " + strconv.Quote(name))
+	}
+	for key := range labels {
+		if !labelName.MatchString(key) || key == "le" {
+			panic("invalid label name " + strconv.Quote(key))
+		}
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic("buckets must be in ascending order")
+	}
+}
+
+// WriteTo writes all histograms to w.
+func (reg *Registry) WriteTo(w io.Writer) (int64, error) {
+	reg.mu.Lock()
+	metrics := append([]*metric{}, reg.metrics...)
+	reg.mu.Unlock()
+
+	// group histograms with the same name, keeping the registration order
+	var names []string
+	byName := map[string][]*metric{}
+	for _, m := range metrics {
+		if _, ok := byName[m.name]; !ok {
+			names = append(names, m.name)
+		}
+		byName[m.name] = append(byName[m.name], m)
+	}
+
+	var b strings.Builder
+	opts := hrtime.HistogramOptions{BinCount: 1}
+	for _, name := range names {
+		group := byName[name]
+		writeHeader(&b, name, group[0].help)
+		for _, m := range group {
+			writeHistogram(&b, name, m.labels, m.source.Histogram(&opts), m.buckets)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves all histograms in the text exposition format.
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = reg.WriteTo(w)
+}
+
+// writeHeader writes HELP and TYPE lines.
+func writeHeader(b *strings.Builder, name, help string) {
+	if help != "" {
+		help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+		fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	}
+	fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+}
+
+// writeHistogram writes the buckets, sum and count of hist in seconds.
+func writeHistogram(b *strings.Builder, name string, labels Labels, hist *hrtime.Histogram, buckets []float64) {
+	bounds := make([]float64, len(buckets))
+	for i, bucket := range buckets {
+		bounds[i] = bucket * 1e9
+	}
+	counts := hist.Cumulative(bounds)
+
+	for i, bucket := range buckets {
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name, formatLabels(labels, formatFloat(bucket)), counts[i])
+	}
+	fmt.Fprintf(b, "%s_bucket%s %d\n", name, formatLabels(labels, "+Inf"), hist.Count)
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, formatLabels(labels, ""), formatFloat(hist.Average*float64(hist.Count)/1e9))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, formatLabels(labels, ""), hist.Count)
+}
+
+// formatLabels formats labels in sorted order and adds le label, when specified.
+func formatLabels(labels Labels, le string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, key+`="`+escapeLabel(labels[key])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabel escapes label value.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats value in the exposition format.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
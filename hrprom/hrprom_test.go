@@ -0,0 +1,133 @@
+package hrprom_test
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/hrprom"
+)
+
+func ExampleRegistry() {
+	latency := hrtime.NewConcurrentRecorder(time.Microsecond, time.Minute, 3)
+	latency.Observe(1500 * time.Microsecond)
+
+	registry := hrprom.NewRegistry()
+	registry.Register("request_duration_seconds", "Request latency.", hrprom.Labels{"route": "/"}, latency, nil)
+	_, _ = registry.WriteTo(os.Stdout)
+}
+
+func TestRegistryScrape(t *testing.T) {
+	home := hrtime.NewConcurrentRecorder(time.Nanosecond, time.Minute, 3)
+	for _, d := range []time.Duration{500 * time.Microsecond, 900 * time.Microsecond, 3 * time.Millisecond} {
+		home.Observe(d)
+	}
+	api := hrtime.NewConcurrentRecorder(time.Nanosecond, time.Minute, 3)
+	api.Observe(2 * time.Second)
+
+	buckets := []float64{0.001, 0.01, 1}
+
+	registry := hrprom.NewRegistry()
+	registry.Register("request_duration_seconds", "Request\nlatency.", hrprom.Labels{"route": "/"}, home, buckets)
+	registry.Register("request_duration_seconds", "", hrprom.Labels{"route": `/api"`}, api, buckets)
+
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.Header.Get("Content-Type") != hrprom.ContentType {
+		t.Errorf("unexpected content type %q", response.Header.Get("Content-Type"))
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP request_duration_seconds Request\nlatency.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{route="/",le="0.001"} 2
+request_duration_seconds_bucket{route="/",le="0.01"} 3
+request_duration_seconds_bucket{route="/",le="1"} 3
+request_duration_seconds_bucket{route="/",le="+Inf"} 3
+request_duration_seconds_sum{route="/"} 0.0044
+request_duration_seconds_count{route="/"} 3
+request_duration_seconds_bucket{route="/api\"",le="0.001"} 0
+request_duration_seconds_bucket{route="/api\"",le="0.01"} 0
+request_duration_seconds_bucket{route="/api\"",le="1"} 0
+request_duration_seconds_bucket{route="/api\"",le="+Inf"} 1
+request_duration_seconds_sum{route="/api\""} 2
+request_duration_seconds_count{route="/api\""} 1
+`
+	if string(body) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, body)
+	}
+}
+
+func TestRegistryDuplicate(t *testing.T) {
+	registry := hrprom.NewRegistry()
+	source := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 2)
+	registry.Register("latency_seconds", "", hrprom.Labels{"a": "1"}, source, nil)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	registry.Register("latency_seconds", "", hrprom.Labels{"a": "1"}, source, nil)
+}
+
+func TestWriteHistogram(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{1e6, 2e6, 3e6, 4e6}, &hrtime.HistogramOptions{BinCount: 2})
+
+	var out strings.Builder
+	if err := hrprom.WriteHistogram(&out, "latency_seconds", "", nil, hist, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.0025"} 2
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 0.01
+latency_seconds_count 4
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestExponentialBuckets(t *testing.T) {
+	buckets := hrprom.ExponentialBuckets(1, 1, 4)
+	expected := []float64{1, math.Sqrt2, 2, 2 * math.Sqrt2, 4}
+	if len(buckets) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, buckets)
+	}
+	for i := range buckets {
+		if math.Abs(buckets[i]-expected[i]) > 1e-12 {
+			t.Errorf("expected %v got %v", expected, buckets)
+			break
+		}
+	}
+
+	buckets = hrprom.ExponentialBuckets(-1, 3, 20)
+	expected = []float64{1, 4, 16, 64}
+	for i := range buckets {
+		if buckets[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected, buckets)
+			break
+		}
+	}
+
+	if hrprom.DefaultBuckets[0] > 1e-6 || hrprom.DefaultBuckets[len(hrprom.DefaultBuckets)-1] != 16 {
+		t.Errorf("unexpected default buckets %v", hrprom.DefaultBuckets)
+	}
+}
@@ -0,0 +1,445 @@
+package hrsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+// connector opens measured connections.
+//
+// Without a base connector it opens connections using the name,
+// like database/sql does for drivers without driver.DriverContext.
+type connector struct {
+	driver *Driver
+	base   driver.Connector
+	name   string
+}
+
+var (
+	_ driver.Connector = (*connector)(nil)
+	_ io.Closer        = (*connector)(nil)
+)
+
+// Connect opens a new connection.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.base == nil {
+		return c.driver.Open(c.name)
+	}
+	base, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{driver: c.driver, base: base}, nil
+}
+
+// Driver returns the measuring driver.
+func (c *connector) Driver() driver.Driver { return c.driver }
+
+// Close closes the base connector, when it implements io.Closer.
+func (c *connector) Close() error {
+	if closer, ok := c.base.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// conn measures the operations of a connection.
+//
+// Optional interfaces not implemented by the wrapped connection
+// return driver.ErrSkip, such that database/sql falls back to
+// the required methods.
+type conn struct {
+	driver *Driver
+	base   driver.Conn
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+// validator is driver.Validator, which is not available in older Go versions.
+type validator interface {
+	IsValid() bool
+}
+
+// Prepare prepares a statement.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares a statement.
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := hrtime.Now()
+	var base driver.Stmt
+	var err error
+	if preparer, ok := c.base.(driver.ConnPrepareContext); ok {
+		base, err = preparer.PrepareContext(ctx, query)
+	} else {
+		base, err = c.base.Prepare(query)
+	}
+	c.driver.observe(Prepare, query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{driver: c.driver, base: base, query: query}, nil
+}
+
+// Close closes the connection.
+func (c *conn) Close() error { return c.base.Close() }
+
+// Begin starts a transaction.
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var base driver.Tx
+	var err error
+	if beginner, ok := c.base.(driver.ConnBeginTx); ok {
+		base, err = beginner.BeginTx(ctx, opts)
+	} else {
+		if opts.Isolation != 0 || opts.ReadOnly {
+			return nil, errors.New("hrsql: driver does not support transaction options")
+		}
+		base, err = c.base.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tx{driver: c.driver, base: base}, nil
+}
+
+// ExecContext executes a query without preparing a statement.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := hrtime.Now()
+	var result driver.Result
+	var err error
+	switch base := c.base.(type) {
+	case driver.ExecerContext:
+		result, err = base.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValues(args); err != nil {
+			return nil, err
+		}
+		result, err = base.Exec(query, values)
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.driver.observe(Exec, query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryContext executes a query without preparing a statement.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := hrtime.Now()
+	var result driver.Rows
+	var err error
+	switch base := c.base.(type) {
+	case driver.QueryerContext:
+		result, err = base.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValues(args); err != nil {
+			return nil, err
+		}
+		result, err = base.Query(query, values)
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.driver.observe(Query, query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{driver: c.driver, base: result, query: query}, nil
+}
+
+// Ping verifies the connection, when supported by the wrapped connection.
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.base.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession resets the connection, when supported by the wrapped connection.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.base.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid reports whether the connection can be reused,
+// when supported by the wrapped connection.
+func (c *conn) IsValid() bool {
+	if validator, ok := c.base.(validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue checks the argument, when supported by the wrapped connection.
+func (c *conn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.base.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// stmt measures the operations of a prepared statement.
+type stmt struct {
+	driver *Driver
+	base   driver.Stmt
+	query  string
+}
+
+var (
+	_ driver.Stmt              = (*stmt)(nil)
+	_ driver.StmtExecContext   = (*stmt)(nil)
+	_ driver.StmtQueryContext  = (*stmt)(nil)
+	_ driver.NamedValueChecker = (*stmt)(nil)
+)
+
+// Close closes the statement.
+func (s *stmt) Close() error { return s.base.Close() }
+
+// NumInput returns the number of placeholder parameters.
+func (s *stmt) NumInput() int { return s.base.NumInput() }
+
+// Exec executes the statement.
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := hrtime.Now()
+	result, err := s.base.Exec(args)
+	s.driver.observe(Exec, s.query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExecContext executes the statement.
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.base.(driver.StmtExecContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Exec(values)
+	}
+
+	start := hrtime.Now()
+	result, err := execer.ExecContext(ctx, args)
+	s.driver.observe(Exec, s.query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Query executes the query.
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := hrtime.Now()
+	result, err := s.base.Query(args)
+	s.driver.observe(Query, s.query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{driver: s.driver, base: result, query: s.query}, nil
+}
+
+// QueryContext executes the query.
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.base.(driver.StmtQueryContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Query(values)
+	}
+
+	start := hrtime.Now()
+	result, err := queryer.QueryContext(ctx, args)
+	s.driver.observe(Query, s.query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{driver: s.driver, base: result, query: s.query}, nil
+}
+
+// CheckNamedValue checks the argument, when supported by the wrapped statement.
+func (s *stmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.base.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	if converter, ok := s.base.(driver.ColumnConverter); ok {
+		v, err := converter.ColumnConverter(value.Ordinal - 1).ConvertValue(value.Value)
+		if err != nil {
+			return err
+		}
+		value.Value = v
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+// rows measures the time spent iterating over rows.
+//
+// Column type interfaces not implemented by the wrapped rows
+// return the same defaults as database/sql uses.
+type rows struct {
+	driver *Driver
+	base   driver.Rows
+	query  string
+
+	elapsed time.Duration
+	once    sync.Once
+}
+
+var (
+	_ driver.RowsNextResultSet              = (*rows)(nil)
+	_ driver.RowsColumnTypeScanType         = (*rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
+	_ driver.RowsColumnTypeLength           = (*rows)(nil)
+	_ driver.RowsColumnTypeNullable         = (*rows)(nil)
+	_ driver.RowsColumnTypePrecisionScale   = (*rows)(nil)
+)
+
+// Columns returns the names of the columns.
+func (r *rows) Columns() []string { return r.base.Columns() }
+
+// ColumnTypeScanType returns the type for scanning the column.
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if typ, ok := r.base.(driver.RowsColumnTypeScanType); ok {
+		return typ.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// ColumnTypeDatabaseTypeName returns the database type of the column, e.g. "VARCHAR".
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if typ, ok := r.base.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return typ.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeLength returns the length of variable length column types.
+func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	if typ, ok := r.base.(driver.RowsColumnTypeLength); ok {
+		return typ.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypeNullable reports whether the column may be null.
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if typ, ok := r.base.(driver.RowsColumnTypeNullable); ok {
+		return typ.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// ColumnTypePrecisionScale returns the precision and scale of decimal column types.
+func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if typ, ok := r.base.(driver.RowsColumnTypePrecisionScale); ok {
+		return typ.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// Next reads the next row into dest.
+func (r *rows) Next(dest []driver.Value) error {
+	start := hrtime.Now()
+	err := r.base.Next(dest)
+	r.elapsed += hrtime.Since(start)
+	if err == io.EOF {
+		r.done(nil)
+	} else if err != nil {
+		r.done(err)
+	}
+	return err
+}
+
+// HasNextResultSet reports whether there is another result set.
+func (r *rows) HasNextResultSet() bool {
+	if next, ok := r.base.(driver.RowsNextResultSet); ok {
+		return next.HasNextResultSet()
+	}
+	return false
+}
+
+// NextResultSet advances to the next result set.
+func (r *rows) NextResultSet() error {
+	if next, ok := r.base.(driver.RowsNextResultSet); ok {
+		return next.NextResultSet()
+	}
+	return io.EOF
+}
+
+// Close closes the rows.
+func (r *rows) Close() error {
+	r.done(nil)
+	return r.base.Close()
+}
+
+// done records the iteration once, err is the error returned by Next.
+func (r *rows) done(err error) {
+	r.once.Do(func() {
+		r.driver.record(Rows, r.query, r.elapsed, err)
+	})
+}
+
+// tx measures committing and rolling back a transaction.
+type tx struct {
+	driver *Driver
+	base   driver.Tx
+}
+
+// Commit commits the transaction.
+func (t *tx) Commit() error {
+	start := hrtime.Now()
+	err := t.base.Commit()
+	t.driver.observe(Commit, "", start, err)
+	return err
+}
+
+// Rollback rolls back the transaction.
+func (t *tx) Rollback() error {
+	start := hrtime.Now()
+	err := t.base.Rollback()
+	t.driver.observe(Rollback, "", start, err)
+	return err
+}
+
+// namedValues converts args to values, named arguments are not supported.
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("hrsql: driver does not support named arguments")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,368 @@
+package hrsql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/hrsql"
+)
+
+// stubDriver is an in-memory driver, where every query returns
+// the number of rows given as the first argument.
+type stubDriver struct {
+	// execer enables driver.ExecerContext and driver.QueryerContext on connections.
+	execer bool
+}
+
+func (d stubDriver) Open(name string) (driver.Conn, error) {
+	if name == "fail" {
+		return nil, errors.New("open failed")
+	}
+	if d.execer {
+		return &stubExecerConn{}, nil
+	}
+	return &stubConn{}, nil
+}
+
+// stubConnectorDriver implements driver.DriverContext and counts the connections.
+type stubConnectorDriver struct {
+	stubDriver
+	connects *int64
+}
+
+func (d stubConnectorDriver) OpenConnector(name string) (driver.Connector, error) {
+	return stubConnector{d}, nil
+}
+
+type stubConnector struct{ driver stubConnectorDriver }
+
+func (c stubConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	atomic.AddInt64(c.driver.connects, 1)
+	return c.driver.Open("")
+}
+func (c stubConnector) Driver() driver.Driver { return c.driver }
+
+type stubConn struct{}
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) {
+	if query == "invalid" {
+		return nil, errors.New("syntax error")
+	}
+	return &stubStmt{}, nil
+}
+func (c *stubConn) Close() error              { return nil }
+func (c *stubConn) Begin() (driver.Tx, error) { return stubTx{}, nil }
+
+type stubExecerConn struct{ stubConn }
+
+func (c *stubExecerConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "invalid" {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *stubExecerConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return newStubRows(args[0].Value), nil
+}
+
+type stubStmt struct{}
+
+func (s *stubStmt) Close() error  { return nil }
+func (s *stubStmt) NumInput() int { return -1 }
+func (s *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return newStubRows(args[0]), nil
+}
+
+type stubRows struct{ remaining int64 }
+
+func newStubRows(count driver.Value) *stubRows { return &stubRows{remaining: count.(int64)} }
+
+func (r *stubRows) Columns() []string { return []string{"n"} }
+func (r *stubRows) Close() error      { return nil }
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.remaining <= 0 {
+		return io.EOF
+	}
+	dest[0] = r.remaining
+	r.remaining--
+	return nil
+}
+
+func (r *stubRows) ColumnTypeScanType(index int) reflect.Type   { return reflect.TypeOf(int64(0)) }
+func (r *stubRows) ColumnTypeDatabaseTypeName(index int) string { return "BIGINT" }
+func (r *stubRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return false, true
+}
+func (r *stubRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	return 19, 0, true
+}
+
+type stubTx struct{}
+
+func (stubTx) Commit() error   { return nil }
+func (stubTx) Rollback() error { return nil }
+
+var stubCount int64
+
+// open registers latency under a unique name and opens a database.
+func open(t *testing.T, latency *hrsql.Driver) *sql.DB {
+	t.Helper()
+	name := "hrsql-stub-" + strconv.FormatInt(atomic.AddInt64(&stubCount, 1), 10)
+	sql.Register(name, latency)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func count(latency *hrsql.Driver, op hrsql.Operation, query string) int {
+	rec := latency.Recorder(hrsql.Key{Operation: op, Query: query})
+	if rec == nil {
+		return 0
+	}
+	return rec.Histogram(&hrtime.HistogramOptions{BinCount: 1}).Count
+}
+
+func query(t *testing.T, db *sql.DB, n int) {
+	t.Helper()
+	rows, err := db.Query("SELECT n FROM numbers LIMIT ?", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for rows.Next() {
+		total++
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if total != n {
+		t.Errorf("expected %d rows got %d", n, total)
+	}
+}
+
+func TestDriverPrepared(t *testing.T) {
+	for _, execer := range []bool{false, true} {
+		latency := hrsql.Wrap(stubDriver{execer: execer}, nil)
+		db := open(t, latency)
+
+		for i := 1; i <= 3; i++ {
+			if _, err := db.Exec("UPDATE numbers SET n = ?", i); err != nil {
+				t.Fatal(err)
+			}
+			query(t, db, i)
+		}
+
+		const update = "UPDATE numbers SET n = ?"
+		const selectn = "SELECT n FROM numbers LIMIT ?"
+		if n := count(latency, hrsql.Exec, update); n != 3 {
+			t.Errorf("execer=%v: expected 3 exec got %d", execer, n)
+		}
+		if n := count(latency, hrsql.Query, selectn); n != 3 {
+			t.Errorf("execer=%v: expected 3 query got %d", execer, n)
+		}
+		if n := count(latency, hrsql.Rows, selectn); n != 3 {
+			t.Errorf("execer=%v: expected 3 rows got %d", execer, n)
+		}
+
+		// without ExecerContext database/sql prepares the statements
+		expected := 0
+		if !execer {
+			expected = 3
+		}
+		if n := count(latency, hrsql.Prepare, update); n != expected {
+			t.Errorf("execer=%v: expected %d prepare got %d", execer, expected, n)
+		}
+		_ = db.Close()
+	}
+}
+
+func TestDriverStmt(t *testing.T) {
+	latency := hrsql.Wrap(stubDriver{}, nil)
+	db := open(t, latency)
+	defer db.Close()
+
+	stmt, err := db.Prepare("SELECT n FROM numbers WHERE n > 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		var n int
+		if err := stmt.QueryRow(int64(1)).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = stmt.Close()
+
+	const normalized = "SELECT n FROM numbers WHERE n > ?"
+	if n := count(latency, hrsql.Prepare, normalized); n != 1 {
+		t.Errorf("expected 1 prepare got %d", n)
+	}
+	if n := count(latency, hrsql.Query, normalized); n != 4 {
+		t.Errorf("expected 4 query got %d", n)
+	}
+
+	if _, err := db.Prepare("invalid"); err == nil {
+		t.Errorf("expected error")
+	}
+	if n := count(latency, hrsql.Prepare, "invalid"); n != 0 {
+		t.Errorf("expected failed prepare to not be recorded as success got %d", n)
+	}
+	if rec := latency.Recorder(hrsql.Key{Operation: hrsql.Prepare, Query: "invalid", Error: true}); rec == nil {
+		t.Errorf("expected failed prepare to be recorded")
+	}
+}
+
+func TestDriverError(t *testing.T) {
+	latency := hrsql.Wrap(stubDriver{execer: true}, nil)
+	db := open(t, latency)
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := db.Exec("invalid"); err == nil {
+			t.Errorf("expected error")
+		}
+	}
+	if _, err := db.Exec("DELETE FROM numbers"); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := latency.Recorder(hrsql.Key{Operation: hrsql.Exec, Query: "invalid", Error: true})
+	if failed == nil || failed.Histogram(&hrtime.HistogramOptions{BinCount: 1}).Count != 2 {
+		t.Errorf("expected 2 failed exec")
+	}
+	if n := count(latency, hrsql.Exec, "DELETE FROM numbers"); n != 1 {
+		t.Errorf("expected 1 exec got %d", n)
+	}
+	if key := (hrsql.Key{Operation: hrsql.Exec, Query: "invalid", Error: true}); key.String() != "exec invalid (error)" {
+		t.Errorf("unexpected key %q", key.String())
+	}
+}
+
+func TestDriverMaxKeys(t *testing.T) {
+	latency := hrsql.Wrap(stubDriver{execer: true}, &hrsql.Options{
+		Normalize: func(query string) string { return query },
+		MaxKeys:   2,
+	})
+	db := open(t, latency)
+	defer db.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := db.Exec("DELETE FROM numbers" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := latency.Keys()
+	if len(keys) != 3 {
+		t.Fatalf("expected 3 keys got %v", keys)
+	}
+	if n := count(latency, hrsql.Overflow, ""); n != 3 {
+		t.Errorf("expected 3 overflow got %d", n)
+	}
+}
+
+func TestDriverColumnTypes(t *testing.T) {
+	latency := hrsql.Wrap(stubDriver{}, nil)
+	db := open(t, latency)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT n FROM numbers LIMIT ?", int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	column := types[0]
+	if column.DatabaseTypeName() != "BIGINT" || column.ScanType() != reflect.TypeOf(int64(0)) {
+		t.Errorf("unexpected type %v %v", column.DatabaseTypeName(), column.ScanType())
+	}
+	if nullable, ok := column.Nullable(); nullable || !ok {
+		t.Errorf("unexpected nullable %v %v", nullable, ok)
+	}
+	if precision, scale, ok := column.DecimalSize(); precision != 19 || scale != 0 || !ok {
+		t.Errorf("unexpected decimal size %v %v %v", precision, scale, ok)
+	}
+	if _, ok := column.Length(); ok {
+		t.Errorf("expected length to be unsupported")
+	}
+}
+
+func TestDriverConnector(t *testing.T) {
+	var connects int64
+	latency := hrsql.Wrap(stubConnectorDriver{stubDriver{execer: true}, &connects}, nil)
+	db := open(t, latency)
+	defer db.Close()
+
+	if _, err := db.Exec("DELETE FROM numbers"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt64(&connects) != 1 {
+		t.Errorf("expected the base connector to be used, got %d connects", connects)
+	}
+	if n := count(latency, hrsql.Exec, "DELETE FROM numbers"); n != 1 {
+		t.Errorf("expected 1 exec got %d", n)
+	}
+}
+
+func TestDriverTx(t *testing.T) {
+	latency := hrsql.Wrap(stubDriver{}, nil)
+	db := open(t, latency)
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Exec("DELETE FROM numbers"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = tx.Rollback()
+
+	if n := count(latency, hrsql.Commit, ""); n != 2 {
+		t.Errorf("expected 2 commit got %d", n)
+	}
+	if n := count(latency, hrsql.Rollback, ""); n != 1 {
+		t.Errorf("expected 1 rollback got %d", n)
+	}
+	if n := count(latency, hrsql.Exec, "DELETE FROM numbers"); n != 2 {
+		t.Errorf("expected 2 exec got %d", n)
+	}
+
+	if _, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true}); err == nil {
+		t.Errorf("expected error for unsupported options")
+	}
+}
+
+func TestDriverOpenError(t *testing.T) {
+	latency := hrsql.Wrap(stubDriver{}, nil)
+	if _, err := latency.Open("fail"); err == nil {
+		t.Errorf("expected error")
+	}
+}
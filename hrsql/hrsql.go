@@ -0,0 +1,384 @@
+// Package hrsql measures database/sql latencies using hrtime.
+//
+// Wrap a driver and register it under a new name:
+//
+//     latency := hrsql.Wrap(&pq.Driver{}, nil)
+//     sql.Register("postgres-hrsql", latency)
+//     db, err := sql.Open("postgres-hrsql", dsn)
+//     ...
+//     fmt.Println(latency)
+//
+// Measurements are grouped by the operation and the normalized query.
+package hrsql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/loov/hrtime/internal/keyed"
+)
+
+// Operation is a measured database operation.
+type Operation int
+
+const (
+	// Prepare is the duration of preparing a statement.
+	Prepare Operation = iota
+	// Exec is the duration of executing a query or a statement.
+	Exec
+	// Query is the duration of a query until rows are returned.
+	Query
+	// Rows is the total duration of iterating over the returned rows,
+	// excluding the time spent between the calls.
+	Rows
+	// Commit is the duration of committing a transaction.
+	Commit
+	// Rollback is the duration of rolling back a transaction.
+	Rollback
+	// Overflow is the duration of any operation with a new key,
+	// after MaxKeys keys have been recorded.
+	Overflow
+)
+
+// Operations lists all the operations.
+var Operations = []Operation{Prepare, Exec, Query, Rows, Commit, Rollback, Overflow}
+
+// String returns the name of the operation.
+func (op Operation) String() string {
+	switch op {
+	case Prepare:
+		return "prepare"
+	case Exec:
+		return "exec"
+	case Query:
+		return "query"
+	case Rows:
+		return "rows"
+	case Commit:
+		return "commit"
+	case Rollback:
+		return "rollback"
+	case Overflow:
+		return "overflow"
+	default:
+		return "Operation(" + strconv.Itoa(int(op)) + ")"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (op Operation) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (op *Operation) UnmarshalText(text []byte) error {
+	for _, o := range Operations {
+		if o.String() == string(text) {
+			*op = o
+			return nil
+		}
+	}
+	return errors.New("unknown operation " + strconv.Quote(string(text)))
+}
+
+// Options is configuration for Wrap.
+type Options struct {
+	// Normalize returns the query used for grouping the measurements,
+	// by default Normalize.
+	Normalize func(query string) string
+
+	// MaxKeys is the maximum number of keys, zero uses DefaultMaxKeys.
+	// Operations with new keys after the limit are recorded under OverflowKey.
+	MaxKeys int
+
+	// Min, Max and SignificantDigits configure the recorders,
+	// see hrtime.NewLogHistogram for details. Zero values use
+	// 1µs, 1 minute and 2 significant digits.
+	Min, Max          time.Duration
+	SignificantDigits int
+}
+
+// DefaultMaxKeys is the default limit for the number of keys.
+const DefaultMaxKeys = 100
+
+var defaultOptions = Options{
+	Normalize:         Normalize,
+	MaxKeys:           DefaultMaxKeys,
+	Min:               time.Microsecond,
+	Max:               time.Minute,
+	SignificantDigits: 2,
+}
+
+// withDefaults returns a copy of opts with zero values replaced by defaults.
+func (opts *Options) withDefaults() Options {
+	if opts == nil {
+		return defaultOptions
+	}
+	result := *opts
+	if result.Normalize == nil {
+		result.Normalize = defaultOptions.Normalize
+	}
+	if result.MaxKeys <= 0 {
+		result.MaxKeys = defaultOptions.MaxKeys
+	}
+	if result.Min == 0 {
+		result.Min = defaultOptions.Min
+	}
+	if result.Max == 0 {
+		result.Max = defaultOptions.Max
+	}
+	if result.SignificantDigits == 0 {
+		result.SignificantDigits = defaultOptions.SignificantDigits
+	}
+	return result
+}
+
+// Key identifies a histogram of operations.
+type Key struct {
+	Operation Operation
+	// Query is the normalized query, empty for Commit and Rollback.
+	Query string
+	// Error reports whether the operation failed.
+	Error bool
+}
+
+// String returns the key as "exec SELECT ..." or "exec SELECT ... (error)".
+func (key Key) String() string {
+	s := key.Operation.String()
+	if key.Query != "" {
+		s += " " + key.Query
+	}
+	if key.Error {
+		s += " (error)"
+	}
+	return s
+}
+
+// OverflowKey is the key of operations, after MaxKeys keys have been recorded.
+var OverflowKey = Key{Operation: Overflow}
+
+// Driver is a driver.Driver that measures the operations of the wrapped driver.
+type Driver struct {
+	base      driver.Driver
+	opts      Options
+	recorders *keyed.Recorders
+}
+
+var _ driver.DriverContext = (*Driver)(nil)
+
+// Wrap creates a driver that measures the operations of base.
+//
+// Nil opts uses Normalize for grouping the queries, at most DefaultMaxKeys
+// keys and records latencies between 1µs and 1 minute with 2 significant digits.
+func Wrap(base driver.Driver, opts *Options) *Driver {
+	config := opts.withDefaults()
+	return &Driver{
+		base:      base,
+		opts:      config,
+		recorders: keyed.New(config.Min, config.Max, config.SignificantDigits, config.MaxKeys, OverflowKey),
+	}
+}
+
+// Open opens a new connection using the wrapped driver.
+func (drv *Driver) Open(name string) (driver.Conn, error) {
+	base, err := drv.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{driver: drv, base: base}, nil
+}
+
+// OpenConnector opens a connector using the wrapped driver,
+// when it implements driver.DriverContext.
+func (drv *Driver) OpenConnector(name string) (driver.Connector, error) {
+	opener, ok := drv.base.(driver.DriverContext)
+	if !ok {
+		return &connector{driver: drv, name: name}, nil
+	}
+	base, err := opener.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &connector{driver: drv, base: base}, nil
+}
+
+// observe records the duration of op since start.
+//
+// Failed operations are recorded under a separate key, except driver.ErrSkip,
+// which only asks database/sql to use a different method.
+func (drv *Driver) observe(op Operation, query string, start time.Duration, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	drv.record(op, query, hrtime.Since(start), err)
+}
+
+// record records elapsed duration of op.
+func (drv *Driver) record(op Operation, query string, elapsed time.Duration, err error) {
+	if query != "" {
+		query = drv.opts.Normalize(query)
+	}
+	drv.recorders.Recorder(Key{Operation: op, Query: query, Error: err != nil}).Observe(elapsed)
+}
+
+// Keys returns the keys of all recorded operations, sorted by query, operation and error.
+func (drv *Driver) Keys() []Key {
+	var keys []Key
+	for _, key := range drv.recorders.Keys() {
+		keys = append(keys, key.(Key))
+	}
+
+	sort.Slice(keys, func(i, k int) bool {
+		a, b := keys[i], keys[k]
+		if a.Query != b.Query {
+			return a.Query < b.Query
+		}
+		if a.Operation != b.Operation {
+			return a.Operation < b.Operation
+		}
+		return !a.Error && b.Error
+	})
+	return keys
+}
+
+// Recorder returns the recorder for key or nil, when there are no such operations.
+func (drv *Driver) Recorder(key Key) *hrtime.ConcurrentRecorder {
+	return drv.recorders.Lookup(key)
+}
+
+// histograms returns histograms of all keys.
+func (drv *Driver) histograms() ([]Key, []*hrtime.Histogram) {
+	keys := drv.Keys()
+	hists := make([]*hrtime.Histogram, len(keys))
+	for i, key := range keys {
+		hists[i] = drv.Recorder(key).Histogram(keyed.HistogramOptions())
+	}
+	return keys, hists
+}
+
+// WriteTo writes histograms of all keys as text to w.
+func (drv *Driver) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	keys, hists := drv.histograms()
+	for i, key := range keys {
+		n, err := io.WriteString(w, key.String()+"\n"+hists[i].String()+"\n")
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// String returns histograms of all keys as text.
+func (drv *Driver) String() string {
+	var buffer strings.Builder
+	_, _ = drv.WriteTo(&buffer)
+	return buffer.String()
+}
+
+// keyHistogram is the JSON form of a single histogram.
+type keyHistogram struct {
+	Operation Operation         `json:"operation"`
+	Query     string            `json:"query,omitempty"`
+	Error     bool              `json:"error,omitempty"`
+	Histogram *hrtime.Histogram `json:"histogram"`
+}
+
+// WriteJSON writes histograms of all keys as JSON to w.
+func (drv *Driver) WriteJSON(w io.Writer) error {
+	keys, hists := drv.histograms()
+	all := make([]keyHistogram, len(keys))
+	for i, key := range keys {
+		all[i] = keyHistogram{
+			Operation: key.Operation,
+			Query:     key.Query,
+			Error:     key.Error,
+			Histogram: hists[i],
+		}
+	}
+	return json.NewEncoder(w).Encode(all)
+}
+
+// Normalize replaces string and numeric literals in query with "?",
+// collapses lists of them into a single item and collapses whitespace,
+// such that queries differing only by the literals are grouped together:
+//
+//     SELECT * FROM users WHERE id = 42 AND name IN ('bob', 'alice')
+//     SELECT * FROM users WHERE id = ? AND name IN (?)
+func Normalize(query string) string {
+	out := make([]byte, 0, len(query))
+	space := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = len(out) > 0
+			i++
+			continue
+		case c == '\'':
+			// skip string literal, '' is an escaped quote
+			i++
+			for i < len(query) {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			c = '?'
+		case isDigit(c) && (i == 0 || !isIdentifier(query[i-1])):
+			for i < len(query) && (isIdentifier(query[i]) || query[i] == '.') {
+				i++
+			}
+			c = '?'
+		default:
+			i++
+		}
+		if space {
+			out = append(out, ' ')
+			space = false
+		}
+		out = append(out, c)
+
+		// collapse lists, e.g. "(?, ?)" and "(?), (?)",
+		// such that the number of items doesn't create new keys
+		out = collapseList(out, "?")
+		out = collapseList(out, "(?)")
+	}
+	return string(out)
+}
+
+// collapseList removes the last item from out, when out ends with "item, item".
+func collapseList(out []byte, item string) []byte {
+	if !bytes.HasSuffix(out, []byte(item)) {
+		return out
+	}
+	rest := bytes.TrimRight(out[:len(out)-len(item)], " ")
+	if !bytes.HasSuffix(rest, []byte(",")) {
+		return out
+	}
+	rest = bytes.TrimRight(rest[:len(rest)-1], " ")
+	if !bytes.HasSuffix(rest, []byte(item)) {
+		return out
+	}
+	return rest
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isIdentifier(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$'
+}
@@ -0,0 +1,126 @@
+package hrsql_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/loov/hrtime/hrsql"
+)
+
+func ExampleWrap() {
+	latency := hrsql.Wrap(stubDriver{}, nil)
+	sql.Register("hrsql-example", latency)
+
+	db, err := sql.Open("hrsql-example", "")
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		_, _ = db.Exec("UPDATE numbers SET n = ?", i)
+	}
+
+	fmt.Println(latency)
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, exp string }{
+		{"SELECT 1", "SELECT ?"},
+		{"  SELECT *\n\tFROM t  ", "SELECT * FROM t"},
+		{"SELECT * FROM users WHERE id = 42 AND name = 'bob'", "SELECT * FROM users WHERE id = ? AND name = ?"},
+		{"SELECT 'it''s', 1.5e10", "SELECT ?"},
+		{"SELECT * FROM t2 WHERE a = $1", "SELECT * FROM t2 WHERE a = $1"},
+		{"INSERT INTO t VALUES (1,2,3)", "INSERT INTO t VALUES (?)"},
+		{"SELECT * FROM t WHERE id IN (1, 2, 3) AND x = ?", "SELECT * FROM t WHERE id IN (?) AND x = ?"},
+		{"SELECT * FROM t WHERE id IN (?, ?)", "SELECT * FROM t WHERE id IN (?)"},
+		{"INSERT INTO t VALUES (1, 'a'), (2, 'b'), (3, 'c')", "INSERT INTO t VALUES (?)"},
+		{"UPDATE t SET a = 1, b = 2", "UPDATE t SET a = ?, b = ?"},
+		{"SELECT 'unterminated", "SELECT ?"},
+	}
+	for _, test := range tests {
+		got := hrsql.Normalize(test.in)
+		if got != test.exp {
+			t.Errorf("%q => %q expected %q", test.in, got, test.exp)
+		}
+	}
+}
+
+func TestOperationText(t *testing.T) {
+	for _, op := range hrsql.Operations {
+		text, _ := op.MarshalText()
+		var decoded hrsql.Operation
+		if err := decoded.UnmarshalText(text); err != nil || decoded != op {
+			t.Errorf("%v: got %v %v", op, decoded, err)
+		}
+	}
+	var op hrsql.Operation
+	if err := op.UnmarshalText([]byte("unknown")); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestDriverOutput(t *testing.T) {
+	latency := hrsql.Wrap(stubDriver{execer: true}, &hrsql.Options{
+		Normalize: strings.ToUpper,
+	})
+	db := open(t, latency)
+	defer db.Close()
+
+	query(t, db, 5)
+	query(t, db, 10)
+	if _, err := db.Exec("delete from numbers"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := latency.Keys()
+	expected := []string{
+		"exec DELETE FROM NUMBERS",
+		"query SELECT N FROM NUMBERS LIMIT ?",
+		"rows SELECT N FROM NUMBERS LIMIT ?",
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, keys)
+	}
+	for i, key := range keys {
+		if key.String() != expected[i] {
+			t.Errorf("key %d: expected %q got %q", i, expected[i], key.String())
+		}
+	}
+
+	text := latency.String()
+	for _, key := range expected {
+		if !strings.Contains(text, key+"\n") {
+			t.Errorf("missing %q:\n%s", key, text)
+		}
+	}
+	if !strings.Contains(text, "avg") {
+		t.Errorf("missing histogram:\n%s", text)
+	}
+
+	var buffer bytes.Buffer
+	if err := latency.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var histograms []struct {
+		Operation hrsql.Operation `json:"operation"`
+		Query     string          `json:"query"`
+		Histogram struct {
+			Count int `json:"count"`
+		} `json:"histogram"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &histograms); err != nil {
+		t.Fatal(err)
+	}
+	if len(histograms) != 3 {
+		t.Fatalf("expected 3 histograms got %d", len(histograms))
+	}
+	got := histograms[2]
+	if got.Operation != hrsql.Rows || got.Query != "SELECT N FROM NUMBERS LIMIT ?" || got.Histogram.Count != 2 {
+		t.Errorf("unexpected histogram %+v", got)
+	}
+}
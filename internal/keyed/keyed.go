@@ -0,0 +1,87 @@
+// Package keyed implements recorders grouped by a key,
+// shared by hrhttp and hrsql.
+package keyed
+
+import (
+	"sync"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+// Recorders contains a concurrent recorder for each key.
+//
+// Keys must be comparable. After MaxKeys keys the measurements
+// with new keys are recorded under the overflow key.
+type Recorders struct {
+	min, max          time.Duration
+	significantDigits int
+	maxKeys           int
+	overflow          interface{}
+
+	mu        sync.RWMutex
+	recorders map[interface{}]*hrtime.ConcurrentRecorder
+}
+
+// New creates recorders with at most maxKeys keys, see
+// hrtime.NewLogHistogram for min, max and significantDigits.
+func New(min, max time.Duration, significantDigits, maxKeys int, overflow interface{}) *Recorders {
+	return &Recorders{
+		min:               min,
+		max:               max,
+		significantDigits: significantDigits,
+		maxKeys:           maxKeys,
+		overflow:          overflow,
+		recorders:         map[interface{}]*hrtime.ConcurrentRecorder{},
+	}
+}
+
+// Recorder returns the recorder for key, creating it when necessary.
+//
+// After MaxKeys keys the recorder for the overflow key is returned instead.
+func (recs *Recorders) Recorder(key interface{}) *hrtime.ConcurrentRecorder {
+	recs.mu.RLock()
+	rec, ok := recs.recorders[key]
+	recs.mu.RUnlock()
+	if ok {
+		return rec
+	}
+
+	recs.mu.Lock()
+	defer recs.mu.Unlock()
+	if rec, ok := recs.recorders[key]; ok {
+		return rec
+	}
+	if len(recs.recorders) >= recs.maxKeys {
+		key = recs.overflow
+		if rec, ok := recs.recorders[key]; ok {
+			return rec
+		}
+	}
+	rec = hrtime.NewConcurrentRecorder(recs.min, recs.max, recs.significantDigits)
+	recs.recorders[key] = rec
+	return rec
+}
+
+// Lookup returns the recorder for key or nil, when there is no such key.
+func (recs *Recorders) Lookup(key interface{}) *hrtime.ConcurrentRecorder {
+	recs.mu.RLock()
+	defer recs.mu.RUnlock()
+	return recs.recorders[key]
+}
+
+// Keys returns all the keys in unspecified order.
+func (recs *Recorders) Keys() []interface{} {
+	recs.mu.RLock()
+	defer recs.mu.RUnlock()
+	keys := make([]interface{}, 0, len(recs.recorders))
+	for key := range recs.recorders {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// HistogramOptions returns the options for the text and JSON output.
+func HistogramOptions() *hrtime.HistogramOptions {
+	return &hrtime.HistogramOptions{BinCount: 10, NiceRange: true, ClampPercentile: 0.999}
+}
@@ -0,0 +1,358 @@
+package hrtime
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Binary lap format
+//
+// The format starts with a header:
+//
+//     magic     "HRTL"
+//     version   byte
+//     spans     byte, 1 when the records are spans
+//     unit      uvarint length + string
+//     ratio     float64 little-endian, NanosecondsPerCount
+//     metadata  uvarint count + (key, value) length-prefixed strings
+//
+// Followed by records until the end of the stream. Laps are stored as
+// zigzag varint delta from the previous lap. Spans are stored as zigzag
+// varint delta from the previous start followed by the span duration.
+
+// lapMagic identifies the binary lap format.
+const lapMagic = "HRTL"
+
+// LapVersion is the current version of the binary lap format.
+const LapVersion = 1
+
+// LapHeader describes the records in the binary lap format.
+type LapHeader struct {
+	// Unit is the unit of the records, "ns" or "tsc".
+	Unit string
+	// NanosecondsPerCount is the calibrated conversion of "tsc" unit to nanoseconds.
+	NanosecondsPerCount float64
+	// Spans is whether the records are spans instead of laps.
+	Spans bool
+	// Metadata contains additional information about the measurements.
+	Metadata map[string]string
+}
+
+// LapWriter writes laps or spans in a compact binary format.
+//
+// Records are delta and varint encoded, which typically
+// takes 1-3 bytes per lap.
+type LapWriter struct {
+	header LapHeader
+	out    *bufio.Writer
+	buf    [binary.MaxVarintLen64]byte
+
+	previous int64
+}
+
+// NewLapWriter writes the header to w and returns a writer for the records.
+//
+// Flush must be called after writing all the records.
+func NewLapWriter(w io.Writer, header LapHeader) (*LapWriter, error) {
+	writer := &LapWriter{
+		header: header,
+		out:    bufio.NewWriter(w),
+	}
+
+	// bufio.Writer errors are sticky, hence they are reported by Flush
+	writer.out.WriteString(lapMagic)
+	writer.out.WriteByte(LapVersion)
+	if header.Spans {
+		writer.out.WriteByte(1)
+	} else {
+		writer.out.WriteByte(0)
+	}
+	writer.writeString(header.Unit)
+
+	var ratio [8]byte
+	binary.LittleEndian.PutUint64(ratio[:], math.Float64bits(header.NanosecondsPerCount))
+	writer.out.Write(ratio[:])
+
+	keys := make([]string, 0, len(header.Metadata))
+	for key := range header.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writer.writeUvarint(uint64(len(keys)))
+	for _, key := range keys {
+		writer.writeString(key)
+		writer.writeString(header.Metadata[key])
+	}
+
+	if err := writer.out.Flush(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (writer *LapWriter) writeUvarint(v uint64) error {
+	n := binary.PutUvarint(writer.buf[:], v)
+	_, err := writer.out.Write(writer.buf[:n])
+	return err
+}
+
+func (writer *LapWriter) writeVarint(v int64) error {
+	n := binary.PutVarint(writer.buf[:], v)
+	_, err := writer.out.Write(writer.buf[:n])
+	return err
+}
+
+func (writer *LapWriter) writeString(s string) error {
+	if err := writer.writeUvarint(uint64(len(s))); err != nil {
+		return err
+	}
+	_, err := writer.out.WriteString(s)
+	return err
+}
+
+// Header returns the header of the records.
+func (writer *LapWriter) Header() LapHeader { return writer.header }
+
+// WriteLap writes a single lap in Unit.
+func (writer *LapWriter) WriteLap(lap int64) error {
+	if writer.header.Spans {
+		return errors.New("cannot write lap to spans")
+	}
+	err := writer.writeVarint(lap - writer.previous)
+	writer.previous = lap
+	return err
+}
+
+// WriteSpan writes a single span in Unit.
+func (writer *LapWriter) WriteSpan(span Span) error {
+	if !writer.header.Spans {
+		return errors.New("cannot write span to laps")
+	}
+	if err := writer.writeVarint(int64(span.Start) - writer.previous); err != nil {
+		return err
+	}
+	writer.previous = int64(span.Start)
+	return writer.writeVarint(int64(span.Duration()))
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (writer *LapWriter) Flush() error {
+	return writer.out.Flush()
+}
+
+// LapReader reads laps or spans written by LapWriter.
+type LapReader struct {
+	header LapHeader
+	in     *bufio.Reader
+
+	previous int64
+}
+
+// NewLapReader reads the header from r and returns a reader for the records.
+func NewLapReader(r io.Reader) (*LapReader, error) {
+	reader := &LapReader{in: bufio.NewReader(r)}
+
+	var prefix [len(lapMagic) + 2]byte
+	if _, err := io.ReadFull(reader.in, prefix[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if string(prefix[:len(lapMagic)]) != lapMagic {
+		return nil, errors.New("invalid lap format")
+	}
+	if version := prefix[len(lapMagic)]; version != LapVersion {
+		return nil, fmt.Errorf("unsupported lap format version %d", version)
+	}
+	reader.header.Spans = prefix[len(lapMagic)+1] == 1
+
+	var err error
+	if reader.header.Unit, err = reader.readString(); err != nil {
+		return nil, err
+	}
+
+	var ratio [8]byte
+	if _, err := io.ReadFull(reader.in, ratio[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	reader.header.NanosecondsPerCount = math.Float64frombits(binary.LittleEndian.Uint64(ratio[:]))
+
+	count, err := binary.ReadUvarint(reader.in)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if count > 0 {
+		reader.header.Metadata = make(map[string]string)
+	}
+	for i := uint64(0); i < count; i++ {
+		key, err := reader.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := reader.readString()
+		if err != nil {
+			return nil, err
+		}
+		reader.header.Metadata[key] = value
+	}
+
+	return reader, nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// maxLapString is the maximum length of a string in the header,
+// which avoids large allocations when reading corrupted input.
+const maxLapString = 1 << 20
+
+func (reader *LapReader) readString() (string, error) {
+	n, err := binary.ReadUvarint(reader.in)
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+	if n > maxLapString {
+		return "", fmt.Errorf("invalid lap format: string length %d exceeds %d", n, maxLapString)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(reader.in, data); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return string(data), nil
+}
+
+// Header returns the header of the records.
+func (reader *LapReader) Header() LapHeader { return reader.header }
+
+// ReadLap reads the next lap in Unit.
+// It returns io.EOF when there are no more laps.
+func (reader *LapReader) ReadLap() (int64, error) {
+	if reader.header.Spans {
+		return 0, errors.New("cannot read lap from spans")
+	}
+	delta, err := binary.ReadVarint(reader.in)
+	if err != nil {
+		return 0, err
+	}
+	reader.previous += delta
+	return reader.previous, nil
+}
+
+// ReadSpan reads the next span in Unit.
+// It returns io.EOF when there are no more spans.
+func (reader *LapReader) ReadSpan() (Span, error) {
+	if !reader.header.Spans {
+		return Span{}, errors.New("cannot read span from laps")
+	}
+	delta, err := binary.ReadVarint(reader.in)
+	if err != nil {
+		return Span{}, err
+	}
+	duration, err := binary.ReadVarint(reader.in)
+	if err != nil {
+		return Span{}, unexpectedEOF(err)
+	}
+	reader.previous += delta
+	return Span{
+		Start:  time.Duration(reader.previous),
+		Finish: time.Duration(reader.previous + duration),
+	}, nil
+}
+
+// RecordTo reads all the remaining records and adds their durations to rec.
+//
+// Measurements in "tsc" unit are converted to nanoseconds using NanosecondsPerCount.
+func (reader *LapReader) RecordTo(rec Recorder) error {
+	scale := 1.0
+	switch reader.header.Unit {
+	case "ns":
+	case "tsc":
+		if reader.header.NanosecondsPerCount <= 0 {
+			return errors.New("missing tsc calibration")
+		}
+		scale = reader.header.NanosecondsPerCount
+	default:
+		return fmt.Errorf("unsupported unit %q", reader.header.Unit)
+	}
+
+	for {
+		var value int64
+		var err error
+		if reader.header.Spans {
+			var span Span
+			span, err = reader.ReadSpan()
+			value = int64(span.Duration())
+		} else {
+			value, err = reader.ReadLap()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rec.Record(float64(value) * scale)
+	}
+}
+
+// WriteLaps writes the laps in the binary lap format to w.
+func (bench *Benchmark) WriteLaps(w io.Writer, metadata map[string]string) error {
+	bench.mustBeCompleted()
+
+	writer, err := NewLapWriter(w, LapHeader{Unit: bench.Unit(), Metadata: metadata})
+	if err != nil {
+		return err
+	}
+	for _, lap := range bench.laps {
+		if err := writer.WriteLap(int64(lap)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// WriteLaps writes the counts in the binary lap format to w.
+func (bench *BenchmarkTSC) WriteLaps(w io.Writer, metadata map[string]string) error {
+	bench.mustBeCompleted()
+
+	writer, err := NewLapWriter(w, LapHeader{
+		Unit:                bench.Unit(),
+		NanosecondsPerCount: approxNanosecondsPerCount(),
+		Metadata:            metadata,
+	})
+	if err != nil {
+		return err
+	}
+	for _, count := range bench.counts {
+		if err := writer.WriteLap(int64(count)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// WriteLaps writes the spans in the binary lap format to w.
+func (bench *Stopwatch) WriteLaps(w io.Writer, metadata map[string]string) error {
+	bench.mustBeCompleted()
+
+	writer, err := NewLapWriter(w, LapHeader{Unit: bench.Unit(), Spans: true, Metadata: metadata})
+	if err != nil {
+		return err
+	}
+	for _, span := range bench.spans {
+		if err := writer.WriteSpan(span); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
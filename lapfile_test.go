@@ -0,0 +1,217 @@
+package hrtime_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleLapReader() {
+	bench := hrtime.NewBenchmark(4096)
+	for bench.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+
+	path := filepath.Join(os.TempDir(), "laps.bin")
+	file, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(file.Name())
+	if err := bench.WriteLaps(file, map[string]string{"name": "Sleep"}); err != nil {
+		panic(err)
+	}
+	_ = file.Close()
+
+	file, err = os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	reader, err := hrtime.NewLapReader(file)
+	if err != nil {
+		panic(err)
+	}
+	hist := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	if err := reader.RecordTo(hist); err != nil {
+		panic(err)
+	}
+	fmt.Println(hist)
+}
+
+func TestLapFormat(t *testing.T) {
+	header := hrtime.LapHeader{
+		Unit:     "ns",
+		Metadata: map[string]string{"name": "Test", "goos": "linux"},
+	}
+	laps := []int64{1000, 1010, 990, 5000, 1000, 0, -5}
+
+	var buffer bytes.Buffer
+	writer, err := hrtime.NewLapWriter(&buffer, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lap := range laps {
+		if err := writer.WriteLap(lap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.WriteSpan(hrtime.Span{}); err == nil {
+		t.Errorf("expected error when writing span to laps")
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := hrtime.NewLapReader(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reader.Header(), header) {
+		t.Errorf("expected header %+v got %+v", header, reader.Header())
+	}
+
+	var got []int64
+	for {
+		lap, err := reader.ReadLap()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, lap)
+	}
+	if !reflect.DeepEqual(got, laps) {
+		t.Errorf("expected %v got %v", laps, got)
+	}
+}
+
+func TestLapFormatSpans(t *testing.T) {
+	bench := hrtime.NewStopwatch(64)
+	for lap := bench.Start(); lap >= 0; lap = bench.Start() {
+		bench.Stop(lap)
+	}
+
+	var buffer bytes.Buffer
+	if err := bench.WriteLaps(&buffer, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := hrtime.NewLapReader(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reader.Header().Spans || reader.Header().Unit != "ns" {
+		t.Fatalf("unexpected header %+v", reader.Header())
+	}
+
+	var spans []hrtime.Span
+	for {
+		span, err := reader.ReadSpan()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		spans = append(spans, span)
+	}
+	if !reflect.DeepEqual(spans, bench.Spans()) {
+		t.Errorf("spans mismatch")
+	}
+}
+
+func TestLapFormatCompact(t *testing.T) {
+	bench := hrtime.NewBenchmark(10000)
+	for bench.Next() {
+	}
+
+	var buffer bytes.Buffer
+	if err := bench.WriteLaps(&buffer, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buffer.Len() > 4*10000 {
+		t.Errorf("expected at most 4 bytes per lap, got %d bytes", buffer.Len())
+	}
+
+	reader, err := hrtime.NewLapReader(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist := hrtime.NewLogHistogram(time.Nanosecond, time.Second, 3)
+	if err := reader.RecordTo(hist); err != nil {
+		t.Fatal(err)
+	}
+	if hist.Count() != 10000 {
+		t.Errorf("expected 10000 measurements got %d", hist.Count())
+	}
+}
+
+func TestLapFormatTSC(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := hrtime.NewLapWriter(&buffer, hrtime.LapHeader{Unit: "tsc", NanosecondsPerCount: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = writer.WriteLap(100)
+	_ = writer.WriteLap(200)
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := hrtime.NewLapReader(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := hrtime.NewTDigest(100)
+	if err := reader.RecordTo(digest); err != nil {
+		t.Fatal(err)
+	}
+	if digest.Percentile(0) != 50 || digest.Percentile(1) != 100 {
+		t.Errorf("expected converted range [50, 100] got [%v, %v]", digest.Percentile(0), digest.Percentile(1))
+	}
+}
+
+func TestLapFormatInvalid(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := hrtime.NewLapWriter(&buffer, hrtime.LapHeader{Unit: "ns", Spans: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = writer.WriteSpan(hrtime.Span{Start: 1000, Finish: 100000})
+	_ = writer.Flush()
+
+	data := buffer.Bytes()
+	if _, err := hrtime.NewLapReader(bytes.NewReader([]byte("XXXX\x01\x00"))); err == nil {
+		t.Errorf("expected error for invalid magic")
+	}
+	if _, err := hrtime.NewLapReader(bytes.NewReader(data[:6])); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected unexpected EOF for truncated header, got %v", err)
+	}
+
+	reader, err := hrtime.NewLapReader(bytes.NewReader(data[:len(data)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.ReadSpan(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected unexpected EOF for truncated span, got %v", err)
+	}
+}
+
+func TestLapFormatStringLength(t *testing.T) {
+	// header with the unit length set to the largest uvarint
+	data := []byte("HRTL\x01\x00\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01ns")
+	_, err := hrtime.NewLapReader(bytes.NewReader(data))
+	if err == nil || err == io.ErrUnexpectedEOF {
+		t.Errorf("expected error for string length, got %v", err)
+	}
+}
@@ -4,6 +4,9 @@ package hrtime
 
 import "time"
 
+// clockSource is the name of the clock used by Now.
+const clockSource = "time.Now"
+
 // Now returns current time.Duration with best possible precision.
 //
 // Now returns time offset from a specific time.
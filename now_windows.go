@@ -6,6 +6,9 @@ import (
 	"unsafe"
 )
 
+// clockSource is the name of the clock used by Now.
+const clockSource = "QueryPerformanceCounter"
+
 // precision timing
 var (
 	modkernel32 = syscall.NewLazyDLL("kernel32.dll")
@@ -0,0 +1,204 @@
+package hrtime
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Arrival defines the schedule of operations for OpenLoop.
+type Arrival interface {
+	// Interval returns the delay between two consecutive operations.
+	Interval(rng *rand.Rand) time.Duration
+}
+
+type constantRate struct{ interval time.Duration }
+
+// ConstantRate schedules operations at a fixed rate per second.
+func ConstantRate(perSecond float64) Arrival {
+	if perSecond <= 0 {
+		panic("rate must be larger than 0")
+	}
+	return constantRate{time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (rate constantRate) Interval(rng *rand.Rand) time.Duration { return rate.interval }
+
+type poissonRate struct{ perSecond float64 }
+
+// PoissonRate schedules operations as a Poisson process with
+// the specified average rate per second.
+func PoissonRate(perSecond float64) Arrival {
+	if perSecond <= 0 {
+		panic("rate must be larger than 0")
+	}
+	return poissonRate{perSecond}
+}
+
+func (rate poissonRate) Interval(rng *rand.Rand) time.Duration {
+	return time.Duration(rng.ExpFloat64() / rate.perSecond * float64(time.Second))
+}
+
+// OpenLoop measures operations started according to a schedule,
+// independently of how long the previous operations took.
+//
+// Closed-loop measurements, such as Benchmark, wait for the previous
+// operation to complete before starting the next one. When an operation
+// stalls, the measurements that would have been made during the stall
+// are omitted, which makes the tail latencies look better than they are.
+// OpenLoop avoids this coordinated omission by measuring latency from the
+// intended start of the operation rather than from the actual start.
+//
+// OpenLoop can be run only once, create a new one for each run.
+type OpenLoop struct {
+	started   int32
+	arrival   Arrival
+	rng       *rand.Rand
+	intended  []time.Duration
+	stopwatch *Stopwatch
+}
+
+// NewOpenLoop creates a new open-loop benchmark using Now.
+// Count defines the number of operations to measure.
+func NewOpenLoop(count int, arrival Arrival) *OpenLoop {
+	return &OpenLoop{
+		arrival:   arrival,
+		rng:       rand.New(rand.NewSource(int64(Now()))),
+		intended:  make([]time.Duration, count),
+		stopwatch: NewStopwatch(count),
+	}
+}
+
+// Run dispatches operations to the specified number of concurrent workers
+// and waits until all of them have completed.
+//
+// When all workers are busy, the operations are queued and the time spent
+// in the queue is included in the corrected latency.
+//
+// Run panics when called more than once.
+func (load *OpenLoop) Run(workers int, fn func()) {
+	if workers <= 0 {
+		panic("must have at least 1 worker")
+	}
+	if !atomic.CompareAndSwapInt32(&load.started, 0, 1) {
+		panic("open loop can only be run once")
+	}
+
+	queue := make(chan time.Duration, len(load.intended))
+	for i := 0; i < workers; i++ {
+		go func() {
+			for intended := range queue {
+				lap := load.stopwatch.Start()
+				load.intended[lap] = intended
+				fn()
+				load.stopwatch.Stop(lap)
+			}
+		}()
+	}
+
+	next := Now()
+	for range load.intended {
+		sleepUntil(next)
+		queue <- next
+		next += load.arrival.Interval(load.rng)
+	}
+	close(queue)
+
+	load.stopwatch.Wait()
+}
+
+// sleepUntil waits until Now reaches t.
+func sleepUntil(t time.Duration) {
+	for {
+		delay := t - Now()
+		if delay <= 0 {
+			return
+		}
+		// time.Sleep is not precise enough for short delays
+		if delay > time.Millisecond {
+			time.Sleep(delay - time.Millisecond)
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
+
+// Spans returns the actual measured time-spans.
+func (load *OpenLoop) Spans() []Span {
+	return load.stopwatch.Spans()
+}
+
+// IntendedSpans returns time-spans starting from the intended start.
+func (load *OpenLoop) IntendedSpans() []Span {
+	spans := load.stopwatch.Spans()
+	for i := range spans {
+		spans[i].Start = load.intended[i]
+	}
+	return spans
+}
+
+// Durations returns the corrected durations measured from the intended start.
+func (load *OpenLoop) Durations() []time.Duration {
+	spans := load.IntendedSpans()
+	durations := make([]time.Duration, len(spans))
+	for i, span := range spans {
+		durations[i] = span.Duration()
+	}
+	return durations
+}
+
+// RawDurations returns the durations measured from the actual start.
+func (load *OpenLoop) RawDurations() []time.Duration {
+	return load.stopwatch.Durations()
+}
+
+// Delays returns how long each operation waited before it was started.
+func (load *OpenLoop) Delays() []time.Duration {
+	spans := load.stopwatch.Spans()
+	delays := make([]time.Duration, len(spans))
+	for i, span := range spans {
+		delays[i] = span.Start - load.intended[i]
+	}
+	return delays
+}
+
+// Name returns name of the benchmark.
+func (load *OpenLoop) Name() string { return "" }
+
+// Unit returns units it measures.
+func (load *OpenLoop) Unit() string { return "ns" }
+
+// Float64s returns all corrected measurements.
+func (load *OpenLoop) Float64s() []float64 {
+	durations := load.Durations()
+	measurements := make([]float64, len(durations))
+	for i := range measurements {
+		measurements[i] = float64(durations[i].Nanoseconds())
+	}
+	return measurements
+}
+
+// Histogram creates an histogram of the corrected durations.
+//
+// It creates binCount bins to distribute the data and uses the
+// 99.9 percentile as the last bucket range. However, for a nicer output
+// it might choose a larger value.
+func (load *OpenLoop) Histogram(binCount int) *Histogram {
+	opts := defaultOptions
+	opts.BinCount = binCount
+
+	return NewDurationHistogram(load.Durations(), &opts)
+}
+
+// RawHistogram creates an histogram of the durations measured from the actual start.
+//
+// It creates binCount bins to distribute the data and uses the
+// 99.9 percentile as the last bucket range. However, for a nicer output
+// it might choose a larger value.
+func (load *OpenLoop) RawHistogram(binCount int) *Histogram {
+	opts := defaultOptions
+	opts.BinCount = binCount
+
+	return NewDurationHistogram(load.RawDurations(), &opts)
+}
@@ -0,0 +1,53 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleOpenLoop() {
+	const numberOfExperiments = 4096
+	load := hrtime.NewOpenLoop(numberOfExperiments, hrtime.PoissonRate(10000))
+	load.Run(4, func() {
+		time.Sleep(100 * time.Microsecond)
+	})
+	fmt.Println(load.Histogram(10))
+	fmt.Println(load.RawHistogram(10))
+}
+
+func TestOpenLoop(t *testing.T) {
+	const N = 64
+	load := hrtime.NewOpenLoop(N, hrtime.ConstantRate(100000))
+	load.Run(2, func() {
+		time.Sleep(1000 * time.Nanosecond)
+	})
+
+	corrected, raw, delays := load.Durations(), load.RawDurations(), load.Delays()
+	if len(corrected) != N || len(raw) != N || len(delays) != N {
+		t.Fatalf("invalid number of measurements %d %d %d", len(corrected), len(raw), len(delays))
+	}
+	for i := range corrected {
+		if delays[i] < 0 {
+			t.Errorf("operation %d started before intended: %v", i, delays[i])
+		}
+		if corrected[i] != raw[i]+delays[i] {
+			t.Errorf("operation %d: corrected %v != raw %v + delay %v", i, corrected[i], raw[i], delays[i])
+		}
+	}
+	t.Log(load.Histogram(10))
+}
+
+func TestOpenLoopRunTwice(t *testing.T) {
+	load := hrtime.NewOpenLoop(4, hrtime.ConstantRate(100000))
+	load.Run(1, func() {})
+
+	defer func() {
+		if r := recover(); r != "open loop can only be run once" {
+			t.Errorf("expected panic got %v", r)
+		}
+	}()
+	load.Run(1, func() {})
+}
@@ -0,0 +1,11 @@
+package hrtime
+
+// Recorder accumulates measurements without keeping all of them in memory.
+//
+// LogHistogram, TDigest, DDSketch and ConcurrentRecorder implement Recorder.
+type Recorder interface {
+	// Record adds a measurement in nanoseconds.
+	Record(ns float64)
+	// Histogram converts the recorded measurements into a Histogram.
+	Histogram(opts *HistogramOptions) *Histogram
+}
@@ -0,0 +1,166 @@
+package hrtime
+
+import (
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrentRecorder is a goroutine-safe Recorder for tracking latencies
+// in production code, e.g. in request handlers:
+//
+//     start := hrtime.Now()
+//     handle(request)
+//     rec.Observe(hrtime.Since(start))
+//
+// Measurements are recorded into several LogHistogram stripes, such that
+// concurrent goroutines rarely contend on the same lock. There are at most
+// GOMAXPROCS stripes, capped at 8, and each stripe allocates its histogram
+// on the first measurement.
+type ConcurrentRecorder struct {
+	min, max          time.Duration
+	significantDigits int
+
+	stripes []recorderStripe
+
+	// indices caches stripe indices per P, such that goroutines
+	// running on the same P tend to use the same stripe
+	indices   sync.Pool
+	nextIndex uint32
+}
+
+// maxRecorderStripes is the maximum number of stripes in ConcurrentRecorder.
+const maxRecorderStripes = 8
+
+// recorderStripe is a single lock protected LogHistogram.
+type recorderStripe struct {
+	mu   sync.Mutex
+	hist *LogHistogram
+	// padding to avoid false sharing between stripes
+	_ [64]byte
+}
+
+// NewConcurrentRecorder creates a new goroutine-safe recorder for values
+// between min and max with the specified number of significant digits.
+//
+// See NewLogHistogram for details about the parameters.
+func NewConcurrentRecorder(min, max time.Duration, significantDigits int) *ConcurrentRecorder {
+	// stripes are allocated lazily, hence validate the parameters early
+	checkLogHistogram(min, max, significantDigits)
+
+	stripeCount := runtime.GOMAXPROCS(0)
+	if stripeCount > maxRecorderStripes {
+		stripeCount = maxRecorderStripes
+	}
+
+	rec := &ConcurrentRecorder{
+		min:               min,
+		max:               max,
+		significantDigits: significantDigits,
+		stripes:           make([]recorderStripe, stripeCount),
+	}
+	rec.indices.New = func() interface{} {
+		index := int(atomic.AddUint32(&rec.nextIndex, 1)-1) % len(rec.stripes)
+		return &index
+	}
+	return rec
+}
+
+// newLogHistogram creates an empty histogram with the recorder configuration.
+func (rec *ConcurrentRecorder) newLogHistogram() *LogHistogram {
+	return NewLogHistogram(rec.min, rec.max, rec.significantDigits)
+}
+
+// stripe picks a stripe for the current goroutine.
+//
+// Go doesn't expose the current P, however sync.Pool keeps
+// a per-P cache, which is used for remembering the stripe index.
+func (rec *ConcurrentRecorder) stripe() *recorderStripe {
+	index := rec.indices.Get().(*int)
+	stripe := &rec.stripes[*index]
+	rec.indices.Put(index)
+	return stripe
+}
+
+// Record adds a measurement in nanoseconds.
+func (rec *ConcurrentRecorder) Record(ns float64) {
+	stripe := rec.stripe()
+	stripe.mu.Lock()
+	if stripe.hist == nil {
+		stripe.hist = rec.newLogHistogram()
+	}
+	stripe.hist.Record(ns)
+	stripe.mu.Unlock()
+}
+
+// Observe adds a measurement.
+func (rec *ConcurrentRecorder) Observe(d time.Duration) {
+	rec.Record(float64(d))
+}
+
+// Snapshot returns all measurements recorded so far.
+func (rec *ConcurrentRecorder) Snapshot() *LogHistogram {
+	snapshot := rec.newLogHistogram()
+	for i := range rec.stripes {
+		stripe := &rec.stripes[i]
+		stripe.mu.Lock()
+		if stripe.hist != nil {
+			snapshot.Merge(stripe.hist)
+		}
+		stripe.mu.Unlock()
+	}
+	return snapshot
+}
+
+// SnapshotAndReset returns all measurements recorded since the last reset
+// and starts a new interval.
+//
+// Recording is not blocked while the snapshot is being merged.
+func (rec *ConcurrentRecorder) SnapshotAndReset() *LogHistogram {
+	var snapshot *LogHistogram
+	for i := range rec.stripes {
+		stripe := &rec.stripes[i]
+
+		stripe.mu.Lock()
+		previous := stripe.hist
+		stripe.hist = nil
+		stripe.mu.Unlock()
+
+		switch {
+		case previous == nil:
+		case snapshot == nil:
+			snapshot = previous
+		default:
+			snapshot.Merge(previous)
+		}
+	}
+	if snapshot == nil {
+		snapshot = rec.newLogHistogram()
+	}
+	return snapshot
+}
+
+// Histogram converts a snapshot of the measurements into a Histogram.
+func (rec *ConcurrentRecorder) Histogram(opts *HistogramOptions) *Histogram {
+	return rec.Snapshot().Histogram(opts)
+}
+
+// WriteStatsTo writes formatted statistics of a snapshot to w.
+func (rec *ConcurrentRecorder) WriteStatsTo(w io.Writer) (int64, error) {
+	return rec.Snapshot().WriteStatsTo(w)
+}
+
+// WriteTo writes formatted statistics and histogram of a snapshot to w.
+func (rec *ConcurrentRecorder) WriteTo(w io.Writer) (int64, error) {
+	return rec.Snapshot().WriteTo(w)
+}
+
+// String returns a string representation of a snapshot.
+func (rec *ConcurrentRecorder) String() string {
+	var buffer strings.Builder
+	_, _ = rec.WriteTo(&buffer)
+	return buffer.String()
+}
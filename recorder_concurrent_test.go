@@ -0,0 +1,110 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+var _ hrtime.Recorder = (*hrtime.ConcurrentRecorder)(nil)
+
+func ExampleConcurrentRecorder() {
+	rec := hrtime.NewConcurrentRecorder(time.Nanosecond, time.Minute, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 64; k++ {
+				start := hrtime.Now()
+				time.Sleep(1000 * time.Nanosecond)
+				rec.Observe(hrtime.Since(start))
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Println(rec.SnapshotAndReset())
+}
+
+func TestConcurrentRecorder(t *testing.T) {
+	rec := hrtime.NewConcurrentRecorder(time.Nanosecond, time.Second, 2)
+
+	const workers, perWorker = 16, 1000
+	record := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for k := 0; k < perWorker; k++ {
+					rec.Observe(time.Duration(i*perWorker + k + 1))
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	record()
+	if n := rec.Snapshot().Count(); n != workers*perWorker {
+		t.Fatalf("expected %d got %d", workers*perWorker, n)
+	}
+
+	interval := rec.SnapshotAndReset()
+	if n := interval.Count(); n != workers*perWorker {
+		t.Fatalf("expected %d got %d", workers*perWorker, n)
+	}
+	if n := rec.Snapshot().Count(); n != 0 {
+		t.Fatalf("expected reset, got %d", n)
+	}
+
+	// record concurrently with taking snapshots
+	done := make(chan struct{})
+	total := int64(0)
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			total += rec.SnapshotAndReset().Count()
+		}
+	}()
+	record()
+	<-done
+	total += rec.SnapshotAndReset().Count()
+	if total != workers*perWorker {
+		t.Fatalf("lost measurements: expected %d got %d", workers*perWorker, total)
+	}
+}
+
+func TestConcurrentRecorderMemory(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(64))
+
+	measure := func(fn func()) uint64 {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		fn()
+		runtime.ReadMemStats(&after)
+		return after.TotalAlloc - before.TotalAlloc
+	}
+
+	single := measure(func() { _ = hrtime.NewLogHistogram(time.Nanosecond, time.Minute, 3) })
+
+	var rec *hrtime.ConcurrentRecorder
+	if allocated := measure(func() { rec = hrtime.NewConcurrentRecorder(time.Nanosecond, time.Minute, 3) }); allocated > single/10 {
+		t.Errorf("empty recorder allocated %d bytes, single histogram %d bytes", allocated, single)
+	}
+
+	allocated := measure(func() {
+		for i := 0; i < 1000; i++ {
+			rec.Observe(time.Duration(i))
+		}
+		_ = rec.SnapshotAndReset()
+	})
+	if allocated > 10*single {
+		t.Errorf("recording allocated %d bytes, single histogram %d bytes", allocated, single)
+	}
+}
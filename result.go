@@ -0,0 +1,313 @@
+package hrtime
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResultVersion is the current version of the Result serialization format.
+const ResultVersion = 1
+
+// tscClockSource is the name of the clock used by TSC.
+const tscClockSource = "RDTSC"
+
+// Result is a serializable benchmark result.
+//
+// Result can be written and read as JSON with WriteJSON and ReadJSON,
+// and the raw measurements as CSV with WriteCSV and ReadCSV.
+type Result struct {
+	// Version is the version of the serialization format.
+	Version int `json:"version"`
+	// Name is the name of the benchmark.
+	Name string `json:"name,omitempty"`
+	// Unit is the unit of Samples, "ns" or "tsc".
+	Unit string `json:"unit"`
+	// Clock is the clock used for measuring, e.g. "time.Now" or "RDTSC".
+	Clock string `json:"clock,omitempty"`
+	// NanosecondsPerCount is the calibrated conversion of "tsc" unit to nanoseconds.
+	NanosecondsPerCount float64 `json:"nanosecondsPerCount,omitempty"`
+
+	// Samples are the measurements in Unit.
+	Samples []float64 `json:"samples,omitempty"`
+	// Spans are the measured time-spans in nanoseconds.
+	Spans []Span `json:"spans,omitempty"`
+
+	// Options are the options used for creating Histogram.
+	Options *HistogramOptions `json:"options,omitempty"`
+	// Histogram is the histogram of the measurements in nanoseconds.
+	Histogram *Histogram `json:"histogram,omitempty"`
+
+	// Environment contains metadata about the environment, e.g. "goos": "linux".
+	//
+	// Results created from benchmarks contain the values from Environment.
+	Environment map[string]string `json:"environment,omitempty"`
+}
+
+// newResult creates a result from measurements in unit.
+func newResult(name, unit, clock string, samples []float64) *Result {
+	opts := defaultOptions
+	result := &Result{
+		Version: ResultVersion,
+		Name:    name,
+		Unit:    unit,
+		Clock:   clock,
+		Samples: samples,
+		Options: &opts,
+
+		Environment: Environment().Map(),
+	}
+	if unit == "tsc" {
+		result.NanosecondsPerCount = approxNanosecondsPerCount()
+	}
+	result.Histogram = result.histogram()
+	return result
+}
+
+// Result returns the serializable result of the benchmark.
+func (bench *Benchmark) Result() *Result {
+	bench.mustBeCompleted()
+	return newResult(bench.Name(), bench.Unit(), clockSource, bench.Float64s())
+}
+
+// Result returns the serializable result of the benchmark.
+func (bench *BenchmarkTSC) Result() *Result {
+	bench.mustBeCompleted()
+	return newResult(bench.Name(), bench.Unit(), tscClockSource, bench.Float64s())
+}
+
+// Result returns the serializable result of the benchmark including the spans.
+func (bench *Stopwatch) Result() *Result {
+	bench.mustBeCompleted()
+	result := newResult(bench.Name(), bench.Unit(), clockSource, bench.Float64s())
+	result.Spans = bench.Spans()
+	return result
+}
+
+// Result returns the serializable result of the benchmark including
+// the spans using the approximate conversion of Count.
+func (bench *StopwatchTSC) Result() *Result {
+	bench.mustBeCompleted()
+	result := newResult(bench.Name(), bench.Unit(), tscClockSource, bench.Float64s())
+	result.Spans = make([]Span, len(bench.spans))
+	for i, span := range bench.spans {
+		result.Spans[i] = Span{
+			Start:  span.Start.ApproxDuration(),
+			Finish: span.Finish.ApproxDuration(),
+		}
+	}
+	return result
+}
+
+// Redact removes identifying information from the environment.
+func (result *Result) Redact() {
+	delete(result.Environment, "hostname")
+}
+
+// WriteTo writes the name, environment and histogram to w.
+func (result *Result) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	printf := func(format string, args ...interface{}) error {
+		n, err := fmt.Fprintf(w, format, args...)
+		written += int64(n)
+		return err
+	}
+
+	if result.Name != "" {
+		if err := printf("name: %s\n", result.Name); err != nil {
+			return written, err
+		}
+	}
+
+	n, err := writeEnvironment(w, result.Environment)
+	written += n
+	if err != nil {
+		return written, err
+	}
+
+	if result.Histogram != nil {
+		n, err := result.Histogram.WriteTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// String returns a string representation of the result.
+func (result *Result) String() string {
+	var buffer strings.Builder
+	_, _ = result.WriteTo(&buffer)
+	return buffer.String()
+}
+
+// Float64s returns all measurements in Unit.
+func (result *Result) Float64s() []float64 {
+	return append(result.Samples[:0:0], result.Samples...)
+}
+
+// nanoseconds returns all measurements in nanoseconds.
+// It returns false when the measurements cannot be converted.
+func (result *Result) nanoseconds() ([]float64, bool) {
+	switch result.Unit {
+	case "ns":
+		return result.Float64s(), true
+	case "tsc":
+		if result.NanosecondsPerCount <= 0 {
+			return nil, false
+		}
+		nanos := make([]float64, len(result.Samples))
+		for i, v := range result.Samples {
+			nanos[i] = v * result.NanosecondsPerCount
+		}
+		return nanos, true
+	default:
+		return nil, false
+	}
+}
+
+// histogram creates a histogram from the samples.
+// It returns nil when the samples cannot be converted to nanoseconds.
+func (result *Result) histogram() *Histogram {
+	nanos, ok := result.nanoseconds()
+	if !ok {
+		return nil
+	}
+	opts := defaultOptions
+	if result.Options != nil {
+		opts = *result.Options
+	}
+	return NewHistogram(nanos, &opts)
+}
+
+// WriteJSON writes the result as JSON to w.
+func (result *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(result)
+}
+
+// ReadJSON reads a result written by WriteJSON.
+//
+// When the result contains samples, the histogram is recalculated from them,
+// such that it can be merged and used for calculating additional statistics.
+func ReadJSON(r io.Reader) (*Result, error) {
+	result := &Result{}
+	if err := json.NewDecoder(r).Decode(result); err != nil {
+		return nil, err
+	}
+	if result.Version <= 0 || result.Version > ResultVersion {
+		return nil, fmt.Errorf("unsupported result version %d", result.Version)
+	}
+	if result.Options != nil {
+		if err := result.Options.validate(); err != nil {
+			return nil, fmt.Errorf("invalid options: %v", err)
+		}
+	}
+
+	if len(result.Samples) > 0 {
+		if hist := result.histogram(); hist != nil {
+			result.Histogram = hist
+		}
+	}
+	if hist := result.Histogram; hist != nil && hist.Width == 0 {
+		hist.Width = 40
+	}
+	return result, nil
+}
+
+// WriteCSV writes the raw measurements as CSV to w.
+//
+// The first column is the lap number and the second column the
+// measurement in Unit. When the result contains spans, the start
+// and finish of the span in nanoseconds are included.
+func (result *Result) WriteCSV(w io.Writer) error {
+	hasSpans := len(result.Spans) == len(result.Samples) && len(result.Spans) > 0
+
+	out := csv.NewWriter(w)
+	header := []string{"lap", result.Unit}
+	if hasSpans {
+		header = append(header, "start", "finish")
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for i, v := range result.Samples {
+		record := []string{strconv.Itoa(i), strconv.FormatFloat(v, 'g', -1, 64)}
+		if hasSpans {
+			span := result.Spans[i]
+			record = append(record,
+				strconv.FormatInt(int64(span.Start), 10),
+				strconv.FormatInt(int64(span.Finish), 10))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// ReadCSV reads measurements written by WriteCSV.
+//
+// CSV doesn't contain the metadata, hence the histogram of "tsc"
+// measurements is nil, because they cannot be converted to nanoseconds.
+func ReadCSV(r io.Reader) (*Result, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("missing header")
+	}
+
+	header := records[0]
+	if len(header) != 2 && len(header) != 4 {
+		return nil, fmt.Errorf("invalid header %q", header)
+	}
+	hasSpans := len(header) == 4
+
+	result := &Result{
+		Version: ResultVersion,
+		Unit:    header[1],
+		Samples: make([]float64, 0, len(records)-1),
+	}
+	for line, record := range records[1:] {
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("line %d: expected %d fields", line+2, len(header))
+		}
+		v, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line+2, err)
+		}
+		result.Samples = append(result.Samples, v)
+
+		if hasSpans {
+			start, err := strconv.ParseInt(record[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line+2, err)
+			}
+			finish, err := strconv.ParseInt(record[3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line+2, err)
+			}
+			result.Spans = append(result.Spans, Span{
+				Start:  time.Duration(start),
+				Finish: time.Duration(finish),
+			})
+		}
+	}
+
+	opts := defaultOptions
+	result.Options = &opts
+	result.Histogram = result.histogram()
+	return result, nil
+}
@@ -0,0 +1,145 @@
+package hrtime_test
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleResult() {
+	bench := hrtime.NewBenchmark(1024)
+	for bench.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+
+	result := bench.Result()
+	result.Name = "Sleep"
+	_ = result.WriteJSON(os.Stdout)
+}
+
+func TestResultJSON(t *testing.T) {
+	bench := hrtime.NewStopwatch(16)
+	for lap := bench.Start(); lap >= 0; lap = bench.Start() {
+		bench.Stop(lap)
+	}
+
+	result := bench.Result()
+	result.Name = "Empty"
+	result.Environment = map[string]string{"goos": "test"}
+
+	var buffer bytes.Buffer
+	if err := result.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), `"version": 1`) {
+		t.Errorf("missing version:\n%s", buffer.String())
+	}
+
+	loaded, err := hrtime.ReadJSON(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Name != result.Name || loaded.Unit != "ns" || loaded.Clock != result.Clock {
+		t.Errorf("metadata mismatch: %+v", loaded)
+	}
+	if !reflect.DeepEqual(loaded.Samples, result.Samples) || !reflect.DeepEqual(loaded.Spans, result.Spans) {
+		t.Errorf("measurements mismatch")
+	}
+	if !reflect.DeepEqual(loaded.Options, result.Options) || loaded.Environment["goos"] != "test" {
+		t.Errorf("options or environment mismatch")
+	}
+	if loaded.Histogram.String() != result.Histogram.String() {
+		t.Errorf("histogram mismatch:\n%v\n%v", loaded.Histogram, result.Histogram)
+	}
+
+	// the loaded histogram can be merged
+	merged := hrtime.Merge(loaded.Histogram, result.Histogram)
+	if merged.Count != 32 {
+		t.Errorf("expected 32 measurements got %d", merged.Count)
+	}
+}
+
+func TestResultJSONHistogramOnly(t *testing.T) {
+	hist := hrtime.NewHistogram([]float64{100, 200, 300, 10000}, &hrtime.HistogramOptions{
+		BinCount:        2,
+		ClampPercentile: 0.5,
+		Layout:          hrtime.LogBins,
+	})
+	result := &hrtime.Result{Version: hrtime.ResultVersion, Unit: "ns", Histogram: hist}
+
+	var buffer bytes.Buffer
+	if err := result.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := hrtime.ReadJSON(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Histogram.String() != hist.String() {
+		t.Errorf("histogram mismatch:\n%v\n%v", loaded.Histogram, hist)
+	}
+}
+
+func TestResultJSONVersion(t *testing.T) {
+	_, err := hrtime.ReadJSON(strings.NewReader(`{"version": 1000, "unit": "ns"}`))
+	if err == nil {
+		t.Errorf("expected error for unsupported version")
+	}
+}
+
+func TestResultJSONInvalidOptions(t *testing.T) {
+	for _, options := range []string{
+		`{}`,
+		`{"edges": [10, 5, 20]}`,
+	} {
+		input := `{"version": 1, "unit": "ns", "samples": [1, 2, 3], "options": ` + options + `}`
+		if _, err := hrtime.ReadJSON(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for options %s", options)
+		}
+	}
+}
+
+func TestResultCSV(t *testing.T) {
+	result := &hrtime.Result{
+		Version: hrtime.ResultVersion,
+		Unit:    "ns",
+		Samples: []float64{10, 20.5},
+		Spans: []hrtime.Span{
+			{Start: 100, Finish: 110},
+			{Start: 105, Finish: 125},
+		},
+	}
+
+	var buffer bytes.Buffer
+	if err := result.WriteCSV(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	expected := "lap,ns,start,finish\n0,10,100,110\n1,20.5,105,125\n"
+	if buffer.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buffer.String())
+	}
+
+	loaded, err := hrtime.ReadCSV(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Samples, result.Samples) || !reflect.DeepEqual(loaded.Spans, result.Spans) {
+		t.Errorf("measurements mismatch: %+v", loaded)
+	}
+	if loaded.Histogram == nil || loaded.Histogram.Count != 2 {
+		t.Errorf("expected histogram")
+	}
+}
+
+func TestResultCSVInvalid(t *testing.T) {
+	for _, input := range []string{"", "lap\n", "lap,ns\n0,x\n"} {
+		if _, err := hrtime.ReadCSV(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
@@ -8,8 +8,8 @@ import (
 
 // Span defines a time.Duration span
 type Span struct {
-	Start  time.Duration
-	Finish time.Duration
+	Start  time.Duration `json:"start"`
+	Finish time.Duration `json:"finish"`
 }
 
 // Duration returns the duration of the time span.
@@ -22,6 +22,7 @@ type Stopwatch struct {
 	nextLap      int32
 	lapsMeasured int32
 	spans        []Span
+	phases       [][]PhaseSpan
 	wait         sync.Mutex
 }
 
@@ -34,6 +35,7 @@ func NewStopwatch(count int) *Stopwatch {
 	bench := &Stopwatch{
 		nextLap: 0,
 		spans:   make([]Span, count),
+		phases:  make([][]PhaseSpan, count),
 	}
 	// lock mutex to ensure Wait() blocks until finalize is called
 	bench.wait.Lock()
@@ -54,7 +56,7 @@ func (bench *Stopwatch) mustBeCompleted() {
 // Call to Stop with -1 is ignored.
 func (bench *Stopwatch) Start() int32 {
 	lap := atomic.AddInt32(&bench.nextLap, 1) - 1
-	if int(lap) > len(bench.spans) {
+	if int(lap) >= len(bench.spans) {
 		return -1
 	}
 	bench.spans[lap].Start = Now()
@@ -68,7 +70,9 @@ func (bench *Stopwatch) Stop(lap int32) {
 	if lap < 0 {
 		return
 	}
-	bench.spans[lap].Finish = Now()
+	finish := Now()
+	bench.spans[lap].Finish = finish
+	bench.finishPhase(lap, finish)
 
 	lapsMeasured := atomic.AddInt32(&bench.lapsMeasured, 1)
 	if int(lapsMeasured) == len(bench.spans) {
@@ -125,6 +129,15 @@ func (bench *Stopwatch) Float64s() []float64 {
 	return measurements
 }
 
+// RecordTo adds all measured durations to rec.
+func (bench *Stopwatch) RecordTo(rec Recorder) {
+	bench.mustBeCompleted()
+
+	for _, span := range bench.spans {
+		rec.Record(float64(span.Duration().Nanoseconds()))
+	}
+}
+
 // Histogram creates an histogram of all the durations.
 //
 // It creates binCount bins to distribute the data and uses the
@@ -0,0 +1,245 @@
+package hrtime
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Attribute is an additional key-value pair attached to a phase.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// PhaseSpan defines a named time.Duration span inside a lap.
+type PhaseSpan struct {
+	Span
+	// Name is the name of the phase.
+	Name string
+	// Lap is the parent lap of the phase.
+	Lap int32
+	// Attributes are optional values attached to the phase.
+	Attributes []Attribute
+}
+
+// Phase starts measuring a named phase of the specified lap.
+// It finishes the previous phase of the lap, if there is one.
+// The last phase is finished by Stop.
+//
+// Phases of a single lap must not be started concurrently
+// and must not be started after the lap has been stopped.
+// The name must not be empty, because CriticalPath reports
+// time not covered by any phase with an empty name.
+//
+// Call to Phase with -1 is ignored.
+func (bench *Stopwatch) Phase(lap int32, name string, attrs ...Attribute) {
+	if lap < 0 {
+		return
+	}
+	if name == "" {
+		panic("phase name must not be empty")
+	}
+	if bench.spans[lap].Finish != 0 {
+		panic("phase started after stop")
+	}
+	now := Now()
+	bench.finishPhase(lap, now)
+	bench.phases[lap] = append(bench.phases[lap], PhaseSpan{
+		Span:       Span{Start: now},
+		Name:       name,
+		Lap:        lap,
+		Attributes: attrs,
+	})
+}
+
+// finishPhase finishes the last unfinished phase of the lap.
+func (bench *Stopwatch) finishPhase(lap int32, finish time.Duration) {
+	phases := bench.phases[lap]
+	if n := len(phases); n > 0 && phases[n-1].Finish == 0 {
+		phases[n-1].Finish = finish
+	}
+}
+
+// Phases returns all measured phases ordered by lap.
+func (bench *Stopwatch) Phases() []PhaseSpan {
+	bench.mustBeCompleted()
+
+	var phases []PhaseSpan
+	for _, lap := range bench.phases {
+		phases = append(phases, lap...)
+	}
+	return phases
+}
+
+// PhaseNames returns names of the phases in the order of first appearance.
+func (bench *Stopwatch) PhaseNames() []string {
+	bench.mustBeCompleted()
+
+	var names []string
+	seen := map[string]bool{}
+	for _, lap := range bench.phases {
+		for _, phase := range lap {
+			if !seen[phase.Name] {
+				seen[phase.Name] = true
+				names = append(names, phase.Name)
+			}
+		}
+	}
+	return names
+}
+
+// PhaseDurations returns measured durations of the named phase.
+func (bench *Stopwatch) PhaseDurations(name string) []time.Duration {
+	bench.mustBeCompleted()
+
+	var durations []time.Duration
+	for _, lap := range bench.phases {
+		for _, phase := range lap {
+			if phase.Name == name {
+				durations = append(durations, phase.Duration())
+			}
+		}
+	}
+	return durations
+}
+
+// PhaseHistogram creates an histogram of the named phase durations.
+//
+// It creates binCount bins to distribute the data and uses the
+// 99.9 percentile as the last bucket range. However, for a nicer output
+// it might choose a larger value.
+func (bench *Stopwatch) PhaseHistogram(name string, binCount int) *Histogram {
+	bench.mustBeCompleted()
+
+	opts := defaultOptions
+	opts.BinCount = binCount
+
+	return NewDurationHistogram(bench.PhaseDurations(name), &opts)
+}
+
+// CriticalPath describes where the time went in the slowest laps.
+type CriticalPath struct {
+	// Percentile is the percentile used to select the tail laps.
+	Percentile float64
+	// Threshold is the smallest lap duration included in the tail.
+	Threshold time.Duration
+	// Laps are the tail laps.
+	Laps []int32
+	// Total is the total duration of the tail laps.
+	Total time.Duration
+	// Phases contains the breakdown per phase in the order of first appearance.
+	// Time not covered by any phase is reported with an empty name.
+	Phases []PhaseBreakdown
+}
+
+// PhaseBreakdown is the total time spent in a phase.
+type PhaseBreakdown struct {
+	Name     string
+	Total    time.Duration
+	Fraction float64
+}
+
+// CriticalPath calculates the per phase breakdown for laps that are
+// at or above the specified percentile, e.g. 0.99.
+func (bench *Stopwatch) CriticalPath(percentile float64) *CriticalPath {
+	bench.mustBeCompleted()
+
+	durations := bench.Durations()
+	sorted := append(durations[:0:0], durations...)
+	sort.Slice(sorted, func(i, k int) bool { return sorted[i] < sorted[k] })
+
+	k := int(percentile * float64(len(sorted)))
+	if k < 0 {
+		k = 0
+	}
+	if k >= len(sorted) {
+		k = len(sorted) - 1
+	}
+
+	path := &CriticalPath{
+		Percentile: percentile,
+		Threshold:  sorted[k],
+	}
+
+	index := map[string]int{}
+	var untracked time.Duration
+	for lap, duration := range durations {
+		if duration < path.Threshold {
+			continue
+		}
+		path.Laps = append(path.Laps, int32(lap))
+		path.Total += duration
+
+		tracked := time.Duration(0)
+		for _, phase := range bench.phases[lap] {
+			i, ok := index[phase.Name]
+			if !ok {
+				i = len(path.Phases)
+				index[phase.Name] = i
+				path.Phases = append(path.Phases, PhaseBreakdown{Name: phase.Name})
+			}
+			path.Phases[i].Total += phase.Duration()
+			tracked += phase.Duration()
+		}
+		untracked += duration - tracked
+	}
+	if untracked > 0 {
+		path.Phases = append(path.Phases, PhaseBreakdown{Total: untracked})
+	}
+
+	for i := range path.Phases {
+		if path.Total > 0 {
+			path.Phases[i].Fraction = float64(path.Phases[i].Total) / float64(path.Total)
+		}
+	}
+
+	return path
+}
+
+// WriteTo writes formatted breakdown to w.
+func (path *CriticalPath) WriteTo(w io.Writer) (int64, error) {
+	n, err := fmt.Fprintf(w, "  p%v: %d laps >= %v;  total %v;\n",
+		path.Percentile*100, len(path.Laps),
+		time.Duration(truncate(float64(path.Threshold), 3)),
+		time.Duration(truncate(float64(path.Total), 3)),
+	)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	maxNameLength := len("(untracked)")
+	for _, phase := range path.Phases {
+		if len(phase.Name) > maxNameLength {
+			maxNameLength = len(phase.Name)
+		}
+	}
+
+	for _, phase := range path.Phases {
+		name := phase.Name
+		if name == "" {
+			name = "(untracked)"
+		}
+		n, err = fmt.Fprintf(w, "  %-*s %10v %5.1f%% %s\n",
+			maxNameLength, name,
+			time.Duration(truncate(float64(phase.Total), 3)),
+			phase.Fraction*100,
+			strings.Repeat("█", int(phase.Fraction*40)),
+		)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// String returns a string representation of the breakdown.
+func (path *CriticalPath) String() string {
+	var buffer strings.Builder
+	_, _ = path.WriteTo(&buffer)
+	return buffer.String()
+}
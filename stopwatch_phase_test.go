@@ -0,0 +1,98 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func ExampleStopwatch_Phase() {
+	const numberOfExperiments = 4096
+	bench := hrtime.NewStopwatch(numberOfExperiments)
+	for i := 0; i < numberOfExperiments; i++ {
+		go func() {
+			lap := bench.Start()
+			defer bench.Stop(lap)
+
+			bench.Phase(lap, "parse")
+			time.Sleep(1000 * time.Nanosecond)
+			bench.Phase(lap, "lookup", hrtime.Attribute{Key: "cache", Value: "miss"})
+			time.Sleep(2000 * time.Nanosecond)
+		}()
+	}
+	bench.Wait()
+	fmt.Println(bench.PhaseHistogram("lookup", 10))
+	fmt.Println(bench.CriticalPath(0.99))
+}
+
+func TestStopwatchPhases(t *testing.T) {
+	const N = 16
+	bench := hrtime.NewStopwatch(N)
+	for i := 0; i < N; i++ {
+		go func() {
+			lap := bench.Start()
+			defer bench.Stop(lap)
+
+			bench.Phase(lap, "parse")
+			time.Sleep(1000 * time.Nanosecond)
+			bench.Phase(lap, "lookup")
+			time.Sleep(1000 * time.Nanosecond)
+			bench.Phase(lap, "encode")
+		}()
+	}
+	bench.Wait()
+
+	names := bench.PhaseNames()
+	if fmt.Sprint(names) != "[parse lookup encode]" {
+		t.Errorf("invalid phase names %v", names)
+	}
+
+	phases := bench.Phases()
+	if len(phases) != 3*N {
+		t.Fatalf("expected %d phases got %d", 3*N, len(phases))
+	}
+	spans := bench.Spans()
+	for _, phase := range phases {
+		span := spans[phase.Lap]
+		if phase.Start < span.Start || phase.Finish > span.Finish || phase.Finish < phase.Start {
+			t.Errorf("phase %v outside of lap %v", phase, span)
+		}
+	}
+
+	if n := len(bench.PhaseDurations("lookup")); n != N {
+		t.Errorf("expected %d lookup durations got %d", N, n)
+	}
+
+	path := bench.CriticalPath(0.5)
+	if len(path.Laps) == 0 {
+		t.Fatal("no laps in critical path")
+	}
+	total := 0.0
+	for _, phase := range path.Phases {
+		total += phase.Fraction
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("fractions do not add up: %v", total)
+	}
+	t.Log(path)
+}
+
+func TestStopwatchPhaseInvalid(t *testing.T) {
+	bench := hrtime.NewStopwatch(2)
+	lap := bench.Start()
+	expectPanic(t, "empty name", func() { bench.Phase(lap, "") })
+	bench.Stop(lap)
+	expectPanic(t, "after stop", func() { bench.Phase(lap, "late") })
+}
+
+func expectPanic(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected panic", name)
+		}
+	}()
+	fn()
+}
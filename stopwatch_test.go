@@ -52,6 +52,24 @@ func TestStopwatch(t *testing.T) {
 	t.Log(bench.Histogram(10))
 }
 
+func TestStopwatchStartAfterLast(t *testing.T) {
+	bench := hrtime.NewStopwatch(2)
+	for lap := bench.Start(); lap >= 0; lap = bench.Start() {
+		bench.Stop(lap)
+	}
+	if lap := bench.Start(); lap != -1 {
+		t.Errorf("expected -1 after the last lap got %d", lap)
+	}
+
+	benchTSC := hrtime.NewStopwatchTSC(2)
+	for lap := benchTSC.Start(); lap >= 0; lap = benchTSC.Start() {
+		benchTSC.Stop(lap)
+	}
+	if lap := benchTSC.Start(); lap != -1 {
+		t.Errorf("expected -1 after the last lap got %d", lap)
+	}
+}
+
 func TestStopwatchTSC(t *testing.T) {
 	bench := hrtime.NewStopwatchTSC(8)
 	for i := 0; i < 8; i++ {
@@ -65,3 +83,44 @@ func TestStopwatchTSC(t *testing.T) {
 	bench.Wait()
 	t.Log(bench.Histogram(10))
 }
+
+func ExampleStopwatch_Worker() {
+	const numberOfExperiments = 4096
+	bench := hrtime.NewStopwatch(numberOfExperiments)
+	for i := 0; i < 8; i++ {
+		go func() {
+			worker := bench.Worker()
+			for lap := worker.Start(); lap >= 0; lap = worker.Start() {
+				time.Sleep(1000 * time.Nanosecond)
+				worker.Stop(lap)
+			}
+		}()
+	}
+	bench.Wait()
+	fmt.Println(bench.Histogram(10))
+}
+
+func TestStopwatchWorker(t *testing.T) {
+	const N = 1000
+	bench := hrtime.NewStopwatch(N)
+	for i := 0; i < 7; i++ {
+		go func() {
+			worker := bench.Worker()
+			for lap := worker.Start(); lap >= 0; lap = worker.Start() {
+				worker.Phase(lap, "work")
+				worker.Stop(lap)
+			}
+		}()
+	}
+	bench.Wait()
+
+	for lap, span := range bench.Spans() {
+		if span.Start == 0 || span.Finish < span.Start {
+			t.Errorf("lap %d not measured: %v", lap, span)
+		}
+	}
+	if n := len(bench.PhaseDurations("work")); n != N {
+		t.Errorf("expected %d phases got %d", N, n)
+	}
+	t.Log(bench.Histogram(10))
+}
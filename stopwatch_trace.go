@@ -0,0 +1,152 @@
+package hrtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// traceEvent is a single event in Chrome trace event format.
+type traceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp float64                `json:"ts"`
+	Duration  float64                `json:"dur,omitempty"`
+	Process   int                    `json:"pid"`
+	Thread    int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes measured spans and phases to w in Chrome trace event format.
+//
+// The output can be loaded in chrome://tracing or https://ui.perfetto.dev.
+// Concurrently running laps are distributed into separate lanes,
+// such that overlapping laps are visible on the timeline.
+func (bench *Stopwatch) WriteChromeTrace(w io.Writer) error {
+	bench.mustBeCompleted()
+
+	lanes := assignLanes(bench.spans)
+
+	origin := time.Duration(0)
+	if len(bench.spans) > 0 {
+		origin = bench.spans[0].Start
+		for _, span := range bench.spans {
+			if span.Start < origin {
+				origin = span.Start
+			}
+		}
+	}
+	micros := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Microsecond)
+	}
+
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+
+	first := true
+	emit := func(ev *traceEvent) error {
+		if !first {
+			if _, err := out.WriteString(","); err != nil {
+				return err
+			}
+		}
+		first = false
+		return enc.Encode(ev)
+	}
+
+	if _, err := out.WriteString(`{"displayTimeUnit":"ns","traceEvents":[`); err != nil {
+		return err
+	}
+
+	laneCount := 0
+	for _, lane := range lanes {
+		if lane+1 > laneCount {
+			laneCount = lane + 1
+		}
+	}
+	for lane := 0; lane < laneCount; lane++ {
+		err := emit(&traceEvent{
+			Name:   "thread_name",
+			Phase:  "M",
+			Thread: lane,
+			Args:   map[string]interface{}{"name": "lane " + strconv.Itoa(lane)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for lap, span := range bench.spans {
+		err := emit(&traceEvent{
+			Name:      "lap " + strconv.Itoa(lap),
+			Category:  "lap",
+			Phase:     "X",
+			Timestamp: micros(span.Start - origin),
+			Duration:  micros(span.Duration()),
+			Thread:    lanes[lap],
+			Args:      map[string]interface{}{"lap": lap},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, phase := range bench.phases[lap] {
+			args := map[string]interface{}{"lap": lap}
+			for _, attr := range phase.Attributes {
+				args[attr.Key] = attr.Value
+			}
+			err := emit(&traceEvent{
+				Name:      phase.Name,
+				Category:  "phase",
+				Phase:     "X",
+				Timestamp: micros(phase.Start - origin),
+				Duration:  micros(phase.Duration()),
+				Thread:    lanes[lap],
+				Args:      args,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := out.WriteString("]}\n"); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// assignLanes assigns each span to the first lane that is free at its start.
+func assignLanes(spans []Span) []int {
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, k int) bool {
+		return spans[order[i]].Start < spans[order[k]].Start
+	})
+
+	lanes := make([]int, len(spans))
+	var laneFinish []time.Duration
+	for _, i := range order {
+		span := spans[i]
+		lane := -1
+		for k, finish := range laneFinish {
+			if finish <= span.Start {
+				lane = k
+				break
+			}
+		}
+		if lane < 0 {
+			lane = len(laneFinish)
+			laneFinish = append(laneFinish, 0)
+		}
+		laneFinish[lane] = span.Finish
+		lanes[i] = lane
+	}
+	return lanes
+}
@@ -0,0 +1,66 @@
+package hrtime_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+func TestStopwatchChromeTrace(t *testing.T) {
+	const N = 16
+	bench := hrtime.NewStopwatch(N)
+	for i := 0; i < N; i++ {
+		go func() {
+			lap := bench.Start()
+			defer bench.Stop(lap)
+
+			bench.Phase(lap, "sleep", hrtime.Attribute{Key: "kind", Value: "short"})
+			time.Sleep(1000 * time.Nanosecond)
+		}()
+	}
+	bench.Wait()
+
+	var buffer bytes.Buffer
+	if err := bench.WriteChromeTrace(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	var trace struct {
+		TraceEvents []struct {
+			Name string                 `json:"name"`
+			Cat  string                 `json:"cat"`
+			Ph   string                 `json:"ph"`
+			Ts   float64                `json:"ts"`
+			Dur  float64                `json:"dur"`
+			Tid  int                    `json:"tid"`
+			Args map[string]interface{} `json:"args"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &trace); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, buffer.String())
+	}
+
+	laps, phases := 0, 0
+	laneFinish := map[int]float64{}
+	for _, ev := range trace.TraceEvents {
+		switch ev.Cat {
+		case "lap":
+			laps++
+			if ev.Ts < laneFinish[ev.Tid] {
+				t.Errorf("overlapping laps in lane %d", ev.Tid)
+			}
+			laneFinish[ev.Tid] = ev.Ts + ev.Dur
+		case "phase":
+			phases++
+			if ev.Args["kind"] != "short" {
+				t.Errorf("missing attribute: %v", ev.Args)
+			}
+		}
+	}
+	if laps != N || phases != N {
+		t.Errorf("expected %d laps and phases, got %d and %d", N, laps, phases)
+	}
+}
@@ -0,0 +1,113 @@
+package hrtime
+
+import (
+	"sync/atomic"
+)
+
+// workerChunkSize is the number of laps a StopwatchWorker claims at once.
+const workerChunkSize = 64
+
+// StopwatchWorker measures laps of a Stopwatch from a single goroutine.
+//
+// Worker claims laps from the Stopwatch in chunks and updates the
+// shared counters once per chunk, which avoids contention when
+// there are many concurrent workers.
+//
+// StopwatchWorker must not be used concurrently.
+type StopwatchWorker struct {
+	bench *Stopwatch
+
+	// next and end are the range of claimed laps.
+	next, end int32
+	// measured is the number of laps stopped, but not yet
+	// added to bench.lapsMeasured.
+	measured int32
+}
+
+// Worker creates a new worker for measuring laps from a single goroutine.
+//
+// The worker must be used until Start returns -1,
+// otherwise Wait may not complete.
+func (bench *Stopwatch) Worker() *StopwatchWorker {
+	return &StopwatchWorker{bench: bench}
+}
+
+// claim claims the next chunk of laps.
+func (worker *StopwatchWorker) claim() bool {
+	bench := worker.bench
+	for {
+		// nextLap is only advanced while there are laps left,
+		// such that repeated claims cannot overflow it
+		start := atomic.LoadInt32(&bench.nextLap)
+		if int(start) >= len(bench.spans) {
+			return false
+		}
+		end := start + workerChunkSize
+		if int(end) > len(bench.spans) {
+			end = int32(len(bench.spans))
+		}
+		if atomic.CompareAndSwapInt32(&bench.nextLap, start, end) {
+			worker.next, worker.end = start, end
+			return true
+		}
+	}
+}
+
+// flush adds locally stopped laps to the stopwatch.
+func (worker *StopwatchWorker) flush() {
+	if worker.measured == 0 {
+		return
+	}
+	bench := worker.bench
+	lapsMeasured := atomic.AddInt32(&bench.lapsMeasured, worker.measured)
+	worker.measured = 0
+	if int(lapsMeasured) == len(bench.spans) {
+		bench.finalize()
+	} else if int(lapsMeasured) > len(bench.spans) {
+		panic("stop called too many times")
+	}
+}
+
+// Start starts measuring a new lap.
+// It returns the lap number to pass in for Stop.
+// It will return -1, when all measurements have been made.
+//
+// Call to Stop with -1 is ignored.
+func (worker *StopwatchWorker) Start() int32 {
+	if worker.next >= worker.end {
+		worker.flush()
+		if !worker.claim() {
+			return -1
+		}
+	}
+
+	lap := worker.next
+	worker.next++
+	worker.bench.spans[lap].Start = Now()
+	return lap
+}
+
+// Phase starts measuring a named phase of the specified lap.
+//
+// Call to Phase with -1 is ignored.
+func (worker *StopwatchWorker) Phase(lap int32, name string, attrs ...Attribute) {
+	worker.bench.Phase(lap, name, attrs...)
+}
+
+// Stop stops measuring the specified lap.
+//
+// Call to Stop with -1 is ignored.
+func (worker *StopwatchWorker) Stop(lap int32) {
+	if lap < 0 {
+		return
+	}
+	finish := Now()
+	worker.bench.spans[lap].Finish = finish
+	worker.bench.finishPhase(lap, finish)
+
+	worker.measured++
+	if worker.next >= worker.end {
+		// the last lap of the chunk, flush to allow Wait to complete
+		worker.flush()
+	}
+}
@@ -0,0 +1,21 @@
+package hrtime
+
+import "testing"
+
+func TestStopwatchWorkerClaimAfterLast(t *testing.T) {
+	bench := NewStopwatch(100)
+	worker := bench.Worker()
+	for lap := worker.Start(); lap >= 0; lap = worker.Start() {
+		worker.Stop(lap)
+	}
+	bench.Wait()
+
+	for i := 0; i < 1000; i++ {
+		if lap := worker.Start(); lap != -1 {
+			t.Fatalf("expected -1 after the last lap got %d", lap)
+		}
+	}
+	if bench.nextLap != 100 {
+		t.Errorf("expected claims to stop at 100 got %d", bench.nextLap)
+	}
+}
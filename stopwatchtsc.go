@@ -55,7 +55,7 @@ func (bench *StopwatchTSC) mustBeCompleted() {
 // Call to Stop with -1 is ignored.
 func (bench *StopwatchTSC) Start() int32 {
 	lap := atomic.AddInt32(&bench.nextLap, 1) - 1
-	if int(lap) > len(bench.spans) {
+	if int(lap) >= len(bench.spans) {
 		return -1
 	}
 	bench.spans[lap].Start = TSC()
@@ -126,6 +126,15 @@ func (bench *StopwatchTSC) Float64s() []float64 {
 	return measurements
 }
 
+// RecordTo adds all measured durations to rec using the approximate conversion of Count.
+func (bench *StopwatchTSC) RecordTo(rec Recorder) {
+	bench.mustBeCompleted()
+
+	for _, span := range bench.spans {
+		rec.Record(float64(span.ApproxDuration().Nanoseconds()))
+	}
+}
+
 // Histogram creates an histogram of all the durations.
 //
 // It creates binCount bins to distribute the data and uses the
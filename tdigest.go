@@ -0,0 +1,235 @@
+package hrtime
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TDigest is a mergeable streaming quantile sketch.
+//
+// It keeps a bounded number of weighted centroids, which are smaller
+// near the tails of the distribution. Hence the extreme percentiles
+// are more accurate than the median.
+//
+// TDigest must not be used concurrently with Record or Merge. Reads,
+// such as Percentile and Histogram, do not modify the digest.
+type TDigest struct {
+	compression float64
+
+	centroids []centroid
+	buffer    []centroid
+
+	total    float64
+	min, max float64
+	sum      float64
+}
+
+// centroid is a weighted mean of nearby measurements.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// NewTDigest creates a new t-digest with the specified compression.
+//
+// Larger compression keeps more centroids and gives more accurate results.
+// A compression of 100 is a reasonable default.
+func NewTDigest(compression float64) *TDigest {
+	if compression < 10 {
+		panic("compression must be at least 10")
+	}
+	return &TDigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Record adds a measurement in nanoseconds.
+func (digest *TDigest) Record(ns float64) {
+	digest.buffer = append(digest.buffer, centroid{mean: ns, weight: 1})
+	digest.total++
+	digest.sum += ns
+	if ns < digest.min {
+		digest.min = ns
+	}
+	if ns > digest.max {
+		digest.max = ns
+	}
+	if len(digest.buffer) >= int(5*digest.compression) {
+		digest.compress()
+	}
+}
+
+// RecordDuration adds a measurement.
+func (digest *TDigest) RecordDuration(d time.Duration) {
+	digest.Record(float64(d))
+}
+
+// Merge adds all measurements from other to digest.
+func (digest *TDigest) Merge(other *TDigest) {
+	if other.total == 0 {
+		return
+	}
+
+	digest.buffer = append(digest.buffer, other.centroids...)
+	digest.buffer = append(digest.buffer, other.buffer...)
+	digest.total += other.total
+	digest.sum += other.sum
+	digest.min = math.Min(digest.min, other.min)
+	digest.max = math.Max(digest.max, other.max)
+	digest.compress()
+}
+
+// scale maps quantile q to the scale used for limiting centroid sizes.
+func (digest *TDigest) scale(q float64) float64 {
+	return digest.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// inverseScale maps scale k back to a quantile.
+func (digest *TDigest) inverseScale(k float64) float64 {
+	return (math.Sin(k*2*math.Pi/digest.compression) + 1) / 2
+}
+
+// compress merges buffered measurements into centroids.
+func (digest *TDigest) compress() {
+	if len(digest.buffer) == 0 {
+		return
+	}
+	digest.centroids = digest.compressed()
+	digest.buffer = digest.buffer[:0]
+}
+
+// compressed returns the centroids with the buffered measurements merged,
+// without modifying the digest.
+func (digest *TDigest) compressed() []centroid {
+	if len(digest.buffer) == 0 {
+		return digest.centroids
+	}
+
+	all := make([]centroid, 0, len(digest.centroids)+len(digest.buffer))
+	all = append(all, digest.centroids...)
+	all = append(all, digest.buffer...)
+	sort.Slice(all, func(i, k int) bool { return all[i].mean < all[k].mean })
+
+	merged := make([]centroid, 0, len(digest.centroids)+1)
+	current := all[0]
+	weightSoFar := 0.0
+	limit := digest.inverseScale(digest.scale(0) + 1)
+	for _, next := range all[1:] {
+		q := (weightSoFar + current.weight + next.weight) / digest.total
+		if q <= limit {
+			current.weight += next.weight
+			current.mean += (next.mean - current.mean) * next.weight / current.weight
+			continue
+		}
+
+		merged = append(merged, current)
+		weightSoFar += current.weight
+		limit = digest.inverseScale(digest.scale(weightSoFar/digest.total) + 1)
+		current = next
+	}
+	return append(merged, current)
+}
+
+// clone returns a compressed copy of the digest.
+func (digest *TDigest) clone() *TDigest {
+	clone := *digest
+	clone.centroids = append(digest.centroids[:0:0], digest.compressed()...)
+	clone.buffer = nil
+	return &clone
+}
+
+// Count returns the number of measurements.
+func (digest *TDigest) Count() int64 { return int64(digest.total) }
+
+// Percentile returns the approximate value at the specified percentile, e.g. 0.99.
+//
+// Percentile does not modify the digest, hence it can be called
+// concurrently with other reads, but not with Record or Merge.
+func (digest *TDigest) Percentile(p float64) float64 {
+	if digest.total == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return digest.min
+	}
+	if p >= 1 {
+		return digest.max
+	}
+
+	centroids := digest.compressed()
+	if len(centroids) == 1 {
+		return centroids[0].mean
+	}
+
+	target := p * digest.total
+
+	// interpolate between minimum and the center of the first centroid
+	first := centroids[0]
+	if target < first.weight/2 {
+		return digest.min + (first.mean-digest.min)*target/(first.weight/2)
+	}
+
+	// interpolate between centroid centers
+	cumulative := first.weight / 2
+	for i := 0; i < len(centroids)-1; i++ {
+		left, right := centroids[i], centroids[i+1]
+		delta := (left.weight + right.weight) / 2
+		if target < cumulative+delta {
+			return left.mean + (right.mean-left.mean)*(target-cumulative)/delta
+		}
+		cumulative += delta
+	}
+
+	// interpolate between the center of the last centroid and maximum
+	last := centroids[len(centroids)-1]
+	return last.mean + (digest.max-last.mean)*(target-cumulative)/(last.weight/2)
+}
+
+// RankError returns the approximate maximum rank error of percentiles.
+func (digest *TDigest) RankError() float64 {
+	return math.Pi / (2 * digest.compression)
+}
+
+func (digest *TDigest) count() int       { return int(digest.total) }
+func (digest *TDigest) minimum() float64 { return digest.min }
+func (digest *TDigest) maximum() float64 { return digest.max }
+func (digest *TDigest) mean() float64    { return digest.sum / digest.total }
+
+func (digest *TDigest) quantile(p float64, _ Interpolation) float64 { return digest.Percentile(p) }
+
+func (digest *TDigest) each(fn func(value float64, count int)) {
+	for _, c := range digest.compressed() {
+		fn(c.mean, int(math.Round(c.weight)))
+	}
+}
+
+// Histogram converts the t-digest into a Histogram.
+func (digest *TDigest) Histogram(opts *HistogramOptions) *Histogram {
+	result := newHistogram(digest.clone(), opts)
+	result.RankError = digest.RankError()
+	return result
+}
+
+// WriteStatsTo writes formatted statistics to w.
+func (digest *TDigest) WriteStatsTo(w io.Writer) (int64, error) {
+	opts := defaultOptions
+	return digest.Histogram(&opts).WriteStatsTo(w)
+}
+
+// WriteTo writes formatted statistics and histogram to w.
+func (digest *TDigest) WriteTo(w io.Writer) (int64, error) {
+	opts := defaultOptions
+	return digest.Histogram(&opts).WriteTo(w)
+}
+
+// String returns a string representation of the t-digest.
+func (digest *TDigest) String() string {
+	var buffer strings.Builder
+	_, _ = digest.WriteTo(&buffer)
+	return buffer.String()
+}
@@ -0,0 +1,104 @@
+package hrtime_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+var (
+	_ hrtime.Recorder = (*hrtime.LogHistogram)(nil)
+	_ hrtime.Recorder = (*hrtime.TDigest)(nil)
+	_ hrtime.Recorder = (*hrtime.DDSketch)(nil)
+)
+
+func ExampleTDigest() {
+	const numberOfExperiments = 4096
+	bench := hrtime.NewBenchmark(numberOfExperiments)
+	for bench.Next() {
+		time.Sleep(1000 * time.Nanosecond)
+	}
+
+	digest := hrtime.NewTDigest(100)
+	bench.RecordTo(digest)
+	fmt.Println(digest)
+}
+
+// lognormal returns n deterministic log-normally distributed values and their sorted copy.
+func lognormal(n int) (values, sorted []float64) {
+	rng := rand.New(rand.NewSource(1))
+	values = make([]float64, n)
+	for i := range values {
+		values[i] = math.Exp(10 + rng.NormFloat64())
+	}
+	sorted = append(values[:0:0], values...)
+	sort.Float64s(sorted)
+	return values, sorted
+}
+
+// rankOf returns the fraction of sorted values less than v.
+func rankOf(sorted []float64, v float64) float64 {
+	return float64(sort.SearchFloat64s(sorted, v)) / float64(len(sorted))
+}
+
+func TestTDigest(t *testing.T) {
+	values, sorted := lognormal(100000)
+
+	a, b := hrtime.NewTDigest(100), hrtime.NewTDigest(100)
+	for i, v := range values {
+		if i%2 == 0 {
+			a.Record(v)
+		} else {
+			b.Record(v)
+		}
+	}
+	a.Merge(b)
+
+	if a.Count() != int64(len(values)) {
+		t.Fatalf("invalid count %v", a.Count())
+	}
+	for _, p := range []float64{0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+		got := a.Percentile(p)
+		if rank := rankOf(sorted, got); math.Abs(rank-p) > a.RankError() {
+			t.Errorf("p%v: got %v with rank %v", p*100, got, rank)
+		}
+	}
+
+	hist := a.Histogram(&hrtime.HistogramOptions{BinCount: 10, NiceRange: true, ClampPercentile: 0.99})
+	total := 0
+	for _, bin := range hist.Bins {
+		total += bin.Count
+	}
+	if total != len(values) {
+		t.Errorf("bins contain %d values expected %d", total, len(values))
+	}
+	t.Log(hist)
+}
+
+func TestTDigestConcurrentReads(t *testing.T) {
+	digest, reference := hrtime.NewTDigest(100), hrtime.NewTDigest(100)
+	for i := 1; i <= 1234; i++ {
+		digest.Record(float64(i))
+		reference.Record(float64(i))
+	}
+	expected := reference.Percentile(0.9)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p := digest.Percentile(0.9); p != expected {
+				t.Errorf("expected %v got %v", expected, p)
+			}
+			_ = digest.Histogram(&hrtime.HistogramOptions{BinCount: 4})
+		}()
+	}
+	wg.Wait()
+}
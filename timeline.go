@@ -0,0 +1,165 @@
+package hrtime
+
+import (
+	"sort"
+	"time"
+)
+
+// ConcurrencyPoint defines the number of spans in flight starting at Time.
+type ConcurrencyPoint struct {
+	Time     time.Duration
+	InFlight int
+}
+
+// Concurrency calculates how many spans were in flight over time.
+//
+// The result is a step function ordered by time, where each point
+// is valid until the next point.
+func Concurrency(spans []Span) []ConcurrencyPoint {
+	type event struct {
+		time  time.Duration
+		delta int
+	}
+
+	events := make([]event, 0, 2*len(spans))
+	for _, span := range spans {
+		events = append(events, event{span.Start, 1}, event{span.Finish, -1})
+	}
+	sort.Slice(events, func(i, k int) bool {
+		if events[i].time == events[k].time {
+			// finish before start, such that adjacent spans don't overlap
+			return events[i].delta < events[k].delta
+		}
+		return events[i].time < events[k].time
+	})
+
+	points := []ConcurrencyPoint{}
+	inflight := 0
+	for _, ev := range events {
+		inflight += ev.delta
+		if n := len(points); n > 0 && points[n-1].Time == ev.time {
+			points[n-1].InFlight = inflight
+			continue
+		}
+		points = append(points, ConcurrencyPoint{Time: ev.time, InFlight: inflight})
+	}
+	return points
+}
+
+// MaxConcurrency returns the maximum number of spans in flight.
+func MaxConcurrency(spans []Span) int {
+	max := 0
+	for _, point := range Concurrency(spans) {
+		if point.InFlight > max {
+			max = point.InFlight
+		}
+	}
+	return max
+}
+
+// ThroughputWindow contains the number of spans in a time window.
+type ThroughputWindow struct {
+	// Start is the start of the window.
+	Start time.Duration
+	// Started is the number of spans started in the window.
+	Started int
+	// Completed is the number of spans finished in the window.
+	Completed int
+	// PerSecond is the number of completed spans per second.
+	PerSecond float64
+}
+
+// Throughput calculates the number of started and completed spans
+// in consecutive windows, starting from the first span.
+func Throughput(spans []Span, window time.Duration) []ThroughputWindow {
+	if window <= 0 {
+		panic("window must be larger than 0")
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	first, last := spans[0].Start, spans[0].Finish
+	for _, span := range spans {
+		if span.Start < first {
+			first = span.Start
+		}
+		if span.Finish > last {
+			last = span.Finish
+		}
+	}
+
+	windows := make([]ThroughputWindow, int((last-first)/window)+1)
+	for i := range windows {
+		windows[i].Start = first + time.Duration(i)*window
+	}
+	for _, span := range spans {
+		windows[(span.Start-first)/window].Started++
+		windows[(span.Finish-first)/window].Completed++
+	}
+	for i := range windows {
+		windows[i].PerSecond = float64(windows[i].Completed) * float64(time.Second) / float64(window)
+	}
+	return windows
+}
+
+// ConcurrencyLatency contains span durations grouped by
+// the number of spans in flight when they started.
+type ConcurrencyLatency struct {
+	// InFlight is the number of spans in flight, including the span itself.
+	InFlight int
+	// Durations are the durations of the spans.
+	Durations []time.Duration
+}
+
+// Histogram creates an histogram of the durations.
+//
+// It creates binCount bins to distribute the data and uses the
+// 99.9 percentile as the last bucket range. However, for a nicer output
+// it might choose a larger value.
+func (level *ConcurrencyLatency) Histogram(binCount int) *Histogram {
+	opts := defaultOptions
+	opts.BinCount = binCount
+
+	return NewDurationHistogram(level.Durations, &opts)
+}
+
+// LatencyByConcurrency groups span durations by the number of spans
+// in flight at the moment the span started.
+//
+// The result is ordered by increasing concurrency.
+func LatencyByConcurrency(spans []Span) []ConcurrencyLatency {
+	starts := make([]time.Duration, len(spans))
+	finishes := make([]time.Duration, len(spans))
+	for i, span := range spans {
+		starts[i] = span.Start
+		finishes[i] = span.Finish
+	}
+	sort.Slice(starts, func(i, k int) bool { return starts[i] < starts[k] })
+	sort.Slice(finishes, func(i, k int) bool { return finishes[i] < finishes[k] })
+
+	// countAtOrBefore returns the number of values <= t.
+	countAtOrBefore := func(values []time.Duration, t time.Duration) int {
+		return sort.Search(len(values), func(i int) bool { return values[i] > t })
+	}
+
+	byLevel := map[int][]time.Duration{}
+	for _, span := range spans {
+		inflight := countAtOrBefore(starts, span.Start) - countAtOrBefore(finishes, span.Start)
+		if inflight < 1 {
+			// zero length span finished at its start
+			inflight = 1
+		}
+		byLevel[inflight] = append(byLevel[inflight], span.Duration())
+	}
+
+	levels := make([]ConcurrencyLatency, 0, len(byLevel))
+	for inflight, durations := range byLevel {
+		levels = append(levels, ConcurrencyLatency{
+			InFlight:  inflight,
+			Durations: durations,
+		})
+	}
+	sort.Slice(levels, func(i, k int) bool { return levels[i].InFlight < levels[k].InFlight })
+	return levels
+}
@@ -0,0 +1,64 @@
+package hrtime_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+var timelineSpans = []hrtime.Span{
+	{Start: 0, Finish: 10},
+	{Start: 5, Finish: 15},
+	{Start: 10, Finish: 20},
+	{Start: 12, Finish: 30},
+}
+
+func TestConcurrency(t *testing.T) {
+	got := hrtime.Concurrency(timelineSpans)
+	exp := []hrtime.ConcurrencyPoint{
+		{Time: 0, InFlight: 1},
+		{Time: 5, InFlight: 2},
+		{Time: 10, InFlight: 2},
+		{Time: 12, InFlight: 3},
+		{Time: 15, InFlight: 2},
+		{Time: 20, InFlight: 1},
+		{Time: 30, InFlight: 0},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %v expected %v", got, exp)
+	}
+	if max := hrtime.MaxConcurrency(timelineSpans); max != 3 {
+		t.Errorf("got max %v expected 3", max)
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	windows := hrtime.Throughput(timelineSpans, 10*time.Nanosecond)
+	if len(windows) != 4 {
+		t.Fatalf("expected 4 windows got %v", windows)
+	}
+	started := []int{2, 2, 0, 0}
+	completed := []int{0, 2, 1, 1}
+	for i, window := range windows {
+		if window.Started != started[i] || window.Completed != completed[i] {
+			t.Errorf("window %d: got %+v", i, window)
+		}
+	}
+	if windows[1].PerSecond != 2e8 {
+		t.Errorf("invalid rate %v", windows[1].PerSecond)
+	}
+}
+
+func TestLatencyByConcurrency(t *testing.T) {
+	levels := hrtime.LatencyByConcurrency(timelineSpans)
+	exp := []hrtime.ConcurrencyLatency{
+		{InFlight: 1, Durations: []time.Duration{10}},
+		{InFlight: 2, Durations: []time.Duration{10, 10}},
+		{InFlight: 3, Durations: []time.Duration{18}},
+	}
+	if !reflect.DeepEqual(levels, exp) {
+		t.Errorf("got %v expected %v", levels, exp)
+	}
+}
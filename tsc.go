@@ -21,6 +21,15 @@ func (count Count) ApproxDuration() time.Duration {
 	return time.Duration(count) * ratioNano / time.Duration(ratioCount)
 }
 
+// approxNanosecondsPerCount returns the calibrated conversion ratio from Count to nanoseconds.
+func approxNanosecondsPerCount() float64 {
+	calibrateOnce.Do(calculateTSCConversion)
+	if ratioCount == 0 {
+		return 0
+	}
+	return float64(ratioNano) / float64(ratioCount)
+}
+
 // TSC reads the current Time Stamp Counter value.
 //
 // Reminder: Time Stamp Count are processor specific and need to be converted to